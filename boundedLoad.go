@@ -0,0 +1,111 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 15:40:17
+ */
+
+package csHash
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// 有界负载一致性哈希（consistent hashing with bounded loads）的负载统计
+// 每个真实节点的容量为 ceil(loadFactor * (总负载+1) * 节点副本数 / 所有节点副本数之和)
+type loadTracker struct {
+	loadFactor float64
+	mu         sync.RWMutex
+	loads      map[string]int64
+	totalLoad  int64
+}
+
+func newLoadTracker(loadFactor float64) *loadTracker {
+	return &loadTracker{
+		loadFactor: loadFactor,
+		loads:      make(map[string]int64),
+	}
+}
+
+func (l *loadTracker) inc(nodeName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loads[nodeName]++
+	l.totalLoad++
+}
+
+func (l *loadTracker) done(nodeName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.loads[nodeName] <= 0 {
+		return
+	}
+	l.loads[nodeName]--
+	l.totalLoad--
+	if l.loads[nodeName] == 0 {
+		delete(l.loads, nodeName)
+	}
+}
+
+// 节点再分配一个负载后是否仍不超过容量
+func (l *loadTracker) acceptable(nodeName string, replicas, totalReplicas int64) bool {
+	if replicas <= 0 || totalReplicas <= 0 {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.loads[nodeName]+1 <= l.capacity(replicas, totalReplicas)
+}
+
+// 调用方需持有l.mu
+func (l *loadTracker) capacity(replicas, totalReplicas int64) int64 {
+	return int64(math.Ceil(l.loadFactor * float64(l.totalLoad+1) * float64(replicas) / float64(totalReplicas)))
+}
+
+// 有界负载模式下，数据分配给nodeName之后调用，增加节点负载
+func (c *ConsistentHash) Inc(nodeName string) {
+	if c.loads == nil {
+		return
+	}
+	c.loads.inc(nodeName)
+}
+
+// 有界负载模式下，nodeName上的数据处理完成后调用，减少节点负载
+func (c *ConsistentHash) Done(nodeName string) {
+	if c.loads == nil {
+		return
+	}
+	c.loads.done(nodeName)
+}
+
+// 有界负载模式下各真实节点的当前负载，未开启时返回空
+func (c *ConsistentHash) Loads() map[string]int64 {
+	loads := make(map[string]int64)
+	if c.loads == nil {
+		return loads
+	}
+	c.loads.mu.RLock()
+	defer c.loads.mu.RUnlock()
+	for nodeName, load := range c.loads.loads {
+		loads[nodeName] = load
+	}
+	return loads
+}
+
+// 有界负载模式下nodeName当前的负载上限，未开启或节点不可用时返回0
+func (c *ConsistentHash) MaxLoad(ctx context.Context, nodeName string) (int64, error) {
+	if c.loads == nil {
+		return 0, nil
+	}
+	nodes, err := c.getRealNodeInfos(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !nodes[nodeName].available() {
+		return 0, nil
+	}
+	c.loads.mu.RLock()
+	defer c.loads.mu.RUnlock()
+	return c.loads.capacity(nodes[nodeName].Replicas, availableReplicas(nodes)), nil
+}
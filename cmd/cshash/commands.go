@@ -0,0 +1,239 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 21:03:16
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/redisHashRing"
+)
+
+// 子命令运行环境
+type env struct {
+	cfg  config
+	ring *redisHashRing.RedisHashRing
+	ch   *csHash.ConsistentHash
+	out  *printer
+}
+
+type command func(ctx context.Context, e *env, args []string) error
+
+var commands = map[string]command{
+	"add-node":    addNode,
+	"remove-node": removeNode,
+	"list-nodes":  listNodes,
+	"lookup":      lookup,
+	"dump":        dump,
+	"version":     version,
+	"stats":       stats,
+	"verify":      verify,
+}
+
+// 解析子命令参数，位置参数可以出现在flag之前，返回位置参数
+func parseArgs(name string, flags *flag.FlagSet, args []string, positional int) ([]string, error) {
+	values := make([]string, 0, positional)
+	for len(args) > 0 {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) > 0 {
+			values = append(values, args[0])
+			args = args[1:]
+		}
+	}
+	if len(values) != positional {
+		return nil, fmt.Errorf("%s: expect %d argument(s), got %d", name, positional, len(values))
+	}
+	return values, nil
+}
+
+func addNode(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("add-node", flag.ContinueOnError)
+	weight := flags.Int64("weight", 1, "node weight")
+	values, err := parseArgs("add-node <name>", flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := e.ch.AddNode(ctx, values[0], *weight); err != nil {
+		return err
+	}
+	return printVersion(ctx, e)
+}
+
+func removeNode(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("remove-node", flag.ContinueOnError)
+	drain := flags.Bool("drain", false, "mark the node draining and remove virtual nodes one by one")
+	values, err := parseArgs("remove-node <name>", flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if *drain {
+		err = e.ch.DrainNode(ctx, values[0])
+	} else {
+		err = e.ch.RemoveNode(ctx, values[0])
+	}
+	if err != nil {
+		return err
+	}
+	return printVersion(ctx, e)
+}
+
+type nodeInfo struct {
+	Name     string           `json:"name"`
+	Replicas int64            `json:"replicas"`
+	State    csHash.NodeState `json:"state"`
+}
+
+func listNodes(ctx context.Context, e *env, args []string) error {
+	if _, err := parseArgs("list-nodes", flag.NewFlagSet("list-nodes", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	nodes, err := e.ring.GetRealNodeInfos(ctx)
+	if err != nil {
+		return err
+	}
+	infos := make([]nodeInfo, 0, len(nodes))
+	for name, node := range nodes {
+		infos = append(infos, nodeInfo{Name: name, Replicas: node.Replicas, State: node.State})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	rows := make([][]string, 0, len(infos))
+	for _, info := range infos {
+		rows = append(rows, []string{info.Name, strconv.FormatInt(info.Replicas, 10), string(info.State)})
+	}
+	return e.out.print(infos, []string{"NAME", "REPLICAS", "STATE"}, rows)
+}
+
+type lookupResult struct {
+	Key          string   `json:"key"`
+	Nodes        []string `json:"nodes"`
+	PreviousNode string   `json:"previous_node"`
+}
+
+func lookup(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("lookup", flag.ContinueOnError)
+	n := flags.Int("n", 1, "number of distinct nodes")
+	values, err := parseArgs("lookup <key>", flags, args, 1)
+	if err != nil {
+		return err
+	}
+	result := lookupResult{Key: values[0]}
+	if result.Nodes, err = e.ch.GetNodes(ctx, result.Key, *n); err != nil {
+		return err
+	}
+	if len(result.Nodes) == 0 {
+		return csHash.ErrNoAvailableNode
+	}
+	if _, result.PreviousNode, err = e.ch.GetNodeWithHistory(ctx, result.Key); err != nil {
+		return err
+	}
+	return e.out.print(result, []string{"KEY", "NODES", "PREVIOUS_NODE"},
+		[][]string{{result.Key, strings.Join(result.Nodes, ","), result.PreviousNode}})
+}
+
+func dump(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	format := flags.String("format", "", "write the snapshot as json or yaml, overrides -o")
+	if _, err := parseArgs("dump", flags, args, 0); err != nil {
+		return err
+	}
+	snapshot, err := e.ch.Export(ctx)
+	if err != nil {
+		return err
+	}
+	if *format != "" {
+		return csHash.WriteRingSnapshot(e.out.w, snapshot, csHash.SnapshotFormat(*format))
+	}
+
+	rows := make([][]string, 0, len(snapshot.HashScores))
+	for _, hashScore := range snapshot.HashScores {
+		virtualNodes := make([]string, 0, len(hashScore.VirtualNodes))
+		for _, virtualNode := range hashScore.VirtualNodes {
+			virtualNodes = append(virtualNodes, fmt.Sprintf("%s@%d", virtualNode.VirtualNodeID, virtualNode.Version))
+		}
+		rows = append(rows, []string{strconv.FormatInt(hashScore.Score, 10), strings.Join(virtualNodes, ",")})
+	}
+	return e.out.print(snapshot, []string{"SCORE", "VIRTUAL_NODES"}, rows)
+}
+
+func version(ctx context.Context, e *env, args []string) error {
+	if _, err := parseArgs("version", flag.NewFlagSet("version", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	return printVersion(ctx, e)
+}
+
+func printVersion(ctx context.Context, e *env) error {
+	version, err := e.ring.GetVersion(ctx)
+	if err != nil {
+		return err
+	}
+	return e.out.print(map[string]int64{"version": version}, []string{"VERSION"}, [][]string{{strconv.FormatInt(version, 10)}})
+}
+
+func stats(ctx context.Context, e *env, args []string) error {
+	if _, err := parseArgs("stats", flag.NewFlagSet("stats", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	stats, err := e.ch.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(stats.Nodes))
+	for _, node := range stats.Nodes {
+		rows = append(rows, []string{
+			node.Name,
+			strconv.FormatInt(node.Replicas, 10),
+			strconv.FormatInt(node.VirtualNodes, 10),
+			fmt.Sprintf("%.2f%%", node.Share*100),
+			fmt.Sprintf("%.2f%%", node.ExpectedShare*100),
+			fmt.Sprintf("%.3f", node.Load),
+		})
+	}
+	if err := e.out.print(stats, []string{"NAME", "REPLICAS", "VIRTUAL_NODES", "SHARE", "EXPECTED", "LOAD"}, rows); err != nil {
+		return err
+	}
+	if e.out.format == outputTable {
+		fmt.Fprintf(e.out.w, "\nversion: %d  virtual nodes: %d  std dev: %.4f  max/min: %.4f\n",
+			stats.Version, stats.VirtualNodes, stats.StdDev, stats.MaxMinRatio)
+	}
+	return nil
+}
+
+var errVerifyFailed = errors.New("ring verification failed")
+
+func verify(ctx context.Context, e *env, args []string) error {
+	if _, err := parseArgs("verify", flag.NewFlagSet("verify", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	problems, err := e.ch.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(problems))
+	for _, problem := range problems {
+		rows = append(rows, []string{problem.NodeName, problem.VirtualNodeID, strconv.FormatInt(problem.Score, 10), problem.Message})
+	}
+	if err := e.out.print(problems, []string{"NODE", "VIRTUAL_NODE", "SCORE", "PROBLEM"}, rows); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return errVerifyFailed
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 20:48:05
+ */
+
+// cshash 哈希环运维工具，直接连接Redis管理redisHashRing保存的哈希环
+//
+//	cshash [全局参数] <子命令> [子命令参数]
+//
+// 子命令：add-node、remove-node、list-nodes、lookup、dump、version、stats、verify
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/redisHashRing"
+)
+
+const usage = `usage: cshash [flags] <command> [args]
+
+commands:
+  add-node <name> [-weight n]   add a node with weight (default 1)
+  remove-node <name> [-drain]   remove a node, -drain removes virtual nodes one by one
+  list-nodes                    list real nodes with replicas and state
+  lookup <key> [-n n]           show the node owning key, or n distinct nodes
+  dump [-format json|yaml]      dump the whole ring
+  version                       show the ring version
+  stats                         show the hash space share of every node
+  verify                        check ring consistency, exit 1 on problems
+
+flags:
+`
+
+// 全局参数
+type config struct {
+	network  string
+	address  string
+	password string
+	ring     string
+	replicas int64
+	hash     string
+	output   string
+}
+
+// -hash为ketama时使用与libketama兼容的模式
+const hashKetama = "ketama"
+
+// 按名称创建ConsistentHash，32位和64位哈希、ketama模式写入的哈希环不能混用
+func newConsistentHash(ring csHash.HashRing, hash string, opts ...csHash.ConsistentHashOption) (*csHash.ConsistentHash, error) {
+	if hash == hashKetama {
+		return csHash.NewKetamaConsistentHash(ring, nil, opts...), nil
+	}
+	if encryptor, err := csHash.NewHashEncryptor(hash); err == nil {
+		return csHash.NewConsistentHash(ring, encryptor, nil, opts...), nil
+	}
+	encryptor, err := csHash.NewHashEncryptor64(hash)
+	if err != nil {
+		return nil, err
+	}
+	return csHash.NewConsistentHash64(ring, encryptor, nil, opts...), nil
+}
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "cshash:", err)
+	}
+	os.Exit(1)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	cfg := config{}
+	flags := flag.NewFlagSet("cshash", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&cfg.network, "network", "tcp", "redis network")
+	flags.StringVar(&cfg.address, "addr", "127.0.0.1:6379", "redis address")
+	flags.StringVar(&cfg.password, "password", "", "redis password")
+	flags.StringVar(&cfg.ring, "ring", "", "hash ring key (required)")
+	flags.Int64Var(&cfg.replicas, "replicas", 5, "virtual nodes per weight, must match the services using the ring")
+	flags.StringVar(&cfg.hash, "hash", csHash.HashMurmur3, fmt.Sprintf("hash function, one of %s, must match the services using the ring",
+		strings.Join(append(append(csHash.HashEncryptorNames(), csHash.HashEncryptor64Names()...), hashKetama), ", ")))
+	flags.StringVar(&cfg.output, "o", outputTable, "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	if cfg.ring == "" {
+		return errors.New("-ring is required")
+	}
+	if cfg.output != outputTable && cfg.output != outputJSON {
+		return fmt.Errorf("unknown output format: %s", cfg.output)
+	}
+
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command: %s", flags.Arg(0))
+	}
+
+	client := redisHashRing.NewClient(cfg.network, cfg.address, cfg.password)
+	ring := redisHashRing.NewRedisHashRing(cfg.ring, client)
+	ch, err := newConsistentHash(ring, cfg.hash, csHash.WithReplicas(cfg.replicas))
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	return command(ctx, &env{
+		cfg:  cfg,
+		ring: ring,
+		ch:   ch,
+		out:  newPrinter(stdout, cfg.output),
+	}, flags.Args()[1:])
+}
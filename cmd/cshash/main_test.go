@@ -0,0 +1,185 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 10:12:36
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// 对同一个miniredis执行子命令，返回标准输出
+type cli struct {
+	t    *testing.T
+	addr string
+}
+
+func newCLI(t *testing.T) *cli {
+	return &cli{t: t, addr: miniredis.RunT(t).Addr()}
+}
+
+func (c *cli) run(args ...string) (string, error) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	err := run(context.Background(), append([]string{"-addr", c.addr, "-ring", "test"}, args...), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+// 执行子命令，出错时直接失败
+func (c *cli) mustRun(args ...string) string {
+	out, err := c.run(args...)
+	if err != nil {
+		c.t.Fatalf("%v: %v", args, err)
+	}
+	return out
+}
+
+// 以JSON输出执行子命令并解析结果
+func (c *cli) runJSON(value any, args ...string) {
+	out := c.mustRun(append([]string{"-o", outputJSON}, args...)...)
+	if err := json.Unmarshal([]byte(out), value); err != nil {
+		c.t.Fatalf("%v: invalid output %s", args, out)
+	}
+}
+
+func TestRunFlags(t *testing.T) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	if err := run(context.Background(), nil, &stdout, &stderr); !errors.Is(err, flag.ErrHelp) || !strings.Contains(stderr.String(), "usage: cshash") {
+		t.Fatalf("expect usage, got %v, stderr: %s", err, stderr.String())
+	}
+	if err := run(context.Background(), []string{"version"}, &stdout, &stderr); err == nil || !strings.Contains(err.Error(), "-ring is required") {
+		t.Fatalf("expect -ring is required, got %v", err)
+	}
+
+	c := newCLI(t)
+	if _, err := c.run("unknown"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("expect unknown command, got %v", err)
+	}
+	if _, err := c.run("-o", "xml", "version"); err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Fatalf("expect unknown output format, got %v", err)
+	}
+	if _, err := c.run("add-node"); err == nil || !strings.Contains(err.Error(), "expect 1 argument(s), got 0") {
+		t.Fatalf("expect missing argument, got %v", err)
+	}
+}
+
+func TestNodeCommands(t *testing.T) {
+	c := newCLI(t)
+	versions := map[string]int64{}
+	c.runJSON(&versions, "add-node", "node_a")
+	if versions["version"] != 1 {
+		t.Fatalf("expect version 1, got %v", versions)
+	}
+	//位置参数可以出现在flag之前
+	c.mustRun("add-node", "node_b", "-weight", "2")
+	c.mustRun("add-node", "-weight", "3", "node_c")
+	if _, err := c.run("add-node", "node_a"); !errors.Is(err, csHash.ErrNodeAlreadyExists) {
+		t.Fatalf("expect ErrNodeAlreadyExists, got %v", err)
+	}
+
+	var infos []nodeInfo
+	c.runJSON(&infos, "list-nodes")
+	if len(infos) != 3 || infos[0] != (nodeInfo{Name: "node_a", Replicas: 5, State: csHash.NodeStateActive}) ||
+		infos[1].Name != "node_b" || infos[1].Replicas != 10 || infos[2].Name != "node_c" || infos[2].Replicas != 15 {
+		t.Fatalf("unexpected nodes: %+v", infos)
+	}
+	if out := c.mustRun("list-nodes"); !strings.HasPrefix(out, "NAME") || !strings.Contains(out, "node_b") {
+		t.Fatalf("unexpected table output: %s", out)
+	}
+
+	c.mustRun("remove-node", "node_a")
+	c.mustRun("remove-node", "-drain", "node_b")
+	c.runJSON(&infos, "list-nodes")
+	if len(infos) != 1 || infos[0].Name != "node_c" {
+		t.Fatalf("expect only node_c, got %+v", infos)
+	}
+	c.runJSON(&versions, "version")
+	if versions["version"] <= 3 {
+		t.Fatalf("expect version to grow after removal, got %v", versions)
+	}
+	if out := c.mustRun("version"); !strings.Contains(out, "VERSION") {
+		t.Fatalf("unexpected table output: %s", out)
+	}
+}
+
+func TestLookupCommand(t *testing.T) {
+	c := newCLI(t)
+	if _, err := c.run("lookup", "data"); !errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Fatalf("expect ErrVirtualNodeNotExists on empty ring, got %v", err)
+	}
+	c.mustRun("add-node", "node_a")
+
+	result := lookupResult{}
+	c.runJSON(&result, "lookup", "data")
+	//上一版本哈希环为空
+	if len(result.Nodes) != 1 || result.Nodes[0] != "node_a" || result.PreviousNode != "" {
+		t.Fatalf("unexpected lookup result: %+v", result)
+	}
+
+	c.mustRun("add-node", "node_b")
+	c.runJSON(&result, "lookup", "-n", "3", "data")
+	if len(result.Nodes) != 2 || result.PreviousNode != "node_a" {
+		t.Fatalf("unexpected lookup result: %+v", result)
+	}
+	if out := c.mustRun("lookup", "data"); !strings.Contains(out, "PREVIOUS_NODE") || !strings.Contains(out, "node_a") {
+		t.Fatalf("unexpected table output: %s", out)
+	}
+}
+
+func TestDumpCommand(t *testing.T) {
+	c := newCLI(t)
+	c.mustRun("add-node", "node_a")
+	c.mustRun("add-node", "node_b")
+
+	for _, format := range []csHash.SnapshotFormat{csHash.SnapshotFormatJSON, csHash.SnapshotFormatYAML} {
+		out := c.mustRun("dump", "-format", string(format))
+		snapshot, err := csHash.ReadRingSnapshot(strings.NewReader(out), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.Version != 2 || len(snapshot.RealNodes) != 2 || len(snapshot.HashScores) != 10 {
+			t.Fatalf("unexpected %s snapshot: %+v", format, snapshot)
+		}
+	}
+	if _, err := c.run("dump", "-format", "xml"); err == nil {
+		t.Fatal("expect unsupported format error")
+	}
+	if out := c.mustRun("dump"); !strings.HasPrefix(out, "SCORE") || !strings.Contains(out, "node_a_1@1") {
+		t.Fatalf("unexpected table output: %s", out)
+	}
+}
+
+func TestStatsAndVerifyCommands(t *testing.T) {
+	c := newCLI(t)
+	c.mustRun("add-node", "node_a")
+	c.mustRun("add-node", "node_b", "-weight", "3")
+
+	stats := csHash.RingStats{}
+	c.runJSON(&stats, "stats")
+	if len(stats.Nodes) != 2 || stats.VirtualNodes != 20 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if out := c.mustRun("stats"); !strings.Contains(out, "EXPECTED") || !strings.Contains(out, "std dev") {
+		t.Fatalf("unexpected table output: %s", out)
+	}
+
+	var problems []csHash.RingProblem
+	c.runJSON(&problems, "verify")
+	if len(problems) != 0 {
+		t.Fatalf("expect no problem, got %+v", problems)
+	}
+	//哈希算法与写入时不一致，虚拟节点的score对不上
+	if _, err := c.run("-hash", csHash.HashFNV1a, "verify"); !errors.Is(err, errVerifyFailed) {
+		t.Fatalf("expect errVerifyFailed, got %v", err)
+	}
+}
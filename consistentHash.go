@@ -17,8 +17,20 @@ type ConsistentHash struct {
 	hashRing  HashRing
 	migrator  Migrator
 	encryptor HashEncryptor
-	logger    Logger
-	opts      ConsistentHashOptions
+	// 使用64位哈希时不为nil，此时encryptor为nil
+	encryptor64 HashEncryptor64
+	// ketama模式，不使用encryptor
+	ketama bool
+	logger Logger
+	opts   ConsistentHashOptions
+	// 哈希环本地缓存，未开启时为nil
+	cache *ringCache
+	// 未开启本地缓存时，按版本号缓存的真实节点信息
+	nodeInfos *nodeInfoCache
+	// 有界负载模式的负载统计，未开启时为nil
+	loads *loadTracker
+	// 停止后台任务
+	cancel context.CancelFunc
 }
 
 func NewConsistentHash(
@@ -27,20 +39,78 @@ func NewConsistentHash(
 	migrator Migrator,
 	opts ...ConsistentHashOption) *ConsistentHash {
 
-	ch := ConsistentHash{
+	return newConsistentHash(ConsistentHash{
 		hashRing:  hashRing,
 		migrator:  migrator,
 		encryptor: encryptor,
-	}
+	}, opts)
+}
+
+// 使用64位哈希，score取值为整个int64，虚拟节点很多时也几乎不会发生score冲突
+// 哈希环上已有的数据必须是同一个HashEncryptor64写入的，32位和64位不能混用
+func NewConsistentHash64(
+	hashRing HashRing,
+	encryptor HashEncryptor64,
+	migrator Migrator,
+	opts ...ConsistentHashOption) *ConsistentHash {
+
+	return newConsistentHash(ConsistentHash{
+		hashRing:    hashRing,
+		migrator:    migrator,
+		encryptor64: encryptor,
+	}, opts)
+}
 
+func newConsistentHash(ch ConsistentHash, opts []ConsistentHashOption) *ConsistentHash {
 	for _, opt := range opts {
 		opt(&ch.opts)
 	}
 
 	ch.opts.repair()
+	ch.logger = ch.opts.logger
+
+	if ch.opts.loadFactor > 0 {
+		ch.loads = newLoadTracker(ch.opts.loadFactor)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch.cancel = cancel
+	ch.nodeInfos = newNodeInfoCache(ch.hashRing)
+	if ch.opts.localCache {
+		ch.cache = newRingCache(ch.hashRing, ch.getAllVirtualNodes)
+		if ch.opts.cacheRefreshInterval > 0 {
+			go ch.cache.poll(ctx, ch.opts.cacheRefreshInterval, ch.logger)
+		}
+		//哈希环支持订阅时，收到修改通知立即刷新
+		if watchableRing, ok := ch.hashRing.(WatchableHashRing); ok {
+			go ch.cache.watch(ctx, watchableRing, ch.logger)
+		}
+	}
 	return &ch
 }
 
+// 停止后台任务，不再使用ConsistentHash时调用
+func (c *ConsistentHash) Close() {
+	c.cancel()
+}
+
+// 哈希环版本号发生变化时，重新加载本地缓存，未开启本地缓存时直接返回
+func (c *ConsistentHash) RefreshCache(ctx context.Context) error {
+	if c.cache == nil {
+		return nil
+	}
+	return c.cache.refresh(ctx)
+}
+
+// 修改哈希环之后刷新本地缓存，修改本身已经成功，刷新失败只记录日志
+func (c *ConsistentHash) refreshCacheAfterChange(ctx context.Context) {
+	// 修改映射数量不改变版本号，本进程的修改完成后直接丢弃缓存的真实节点信息
+	c.nodeInfos.reset()
+	if err := c.RefreshCache(ctx); err != nil && c.logger != nil {
+		c.logger.Warn("refresh ring cache failed", "err", err)
+	}
+}
+
 // 添加节点
 func (c *ConsistentHash) AddNode(ctx context.Context, nodeName string, weight int64) error {
 	if err := c.hashRing.Lock(ctx, c.opts.lockExpireSeconds); err != nil {
@@ -49,6 +119,12 @@ func (c *ConsistentHash) AddNode(ctx context.Context, nodeName string, weight in
 
 	defer c.hashRing.Unlock(ctx)
 
+	// 保存修改前的快照，修改完成后再保存一次
+	if err := c.saveSnapshot(ctx); err != nil {
+		return err
+	}
+	defer c.saveSnapshotAfterChange(ctx)
+
 	// 先判断RealNode中是否有该节点
 	// CRUD操作保持一致
 	nodes, err := c.hashRing.GetRealNodes(ctx)
@@ -59,25 +135,75 @@ func (c *ConsistentHash) AddNode(ctx context.Context, nodeName string, weight in
 	if replicas := nodes[nodeName]; replicas > 0 {
 		return ErrNodeAlreadyExists
 	}
+	if err := c.beginOperation(ctx); err != nil {
+		return err
+	}
+
+	if c.ketama {
+		return c.rebalanceKetama(ctx, nodes, withKetamaWeight(nodes, nodeName, max(weight, 1)))
+	}
 
 	//需要映射的节点数量
 	nodeReplicas := repairWeight(weight) * c.opts.replicas
+	virtualNodes := c.getVirtualNodeEntries(nodeName, 1, nodeReplicas)
+
+	// 插入虚拟节点之前，先计算出新虚拟节点负责的区间原本属于哪个真实节点
+	arcs, err := c.getMigrationArcs(ctx, virtualNodes, nil)
+	if err != nil {
+		return err
+	}
+
 	err = c.hashRing.AddRealNode(ctx, nodeName, nodeReplicas)
 	if err != nil {
 		return err
 	}
 
 	//将虚拟节点插入到hash环中
-	for i := int64(1); i <= nodeReplicas; i++ {
-		virtualNodeID := getVirtualNodeID(nodeName, i)
-		virtualNodeScore := c.encryptor.Encrypt(virtualNodeID)
+	if err := c.applyVirtualNodes(ctx, virtualNodes, true); err != nil {
+		return err
+	}
+	c.refreshCacheAfterChange(ctx)
+	return c.migrate(ctx, arcs)
+}
 
-		_, err := c.hashRing.AddVirtualNode(ctx, int64(virtualNodeScore), virtualNodeID)
-		if err != nil {
-			return err
-		}
+// 计算nodeName的[start, end]号虚拟节点及其score
+func (c *ConsistentHash) getVirtualNodeEntries(nodeName string, start, end int64) []VirtualNodeEntry {
+	virtualNodes := make([]VirtualNodeEntry, 0, max(end-start+1, 0))
+	for i := start; i <= end; i++ {
+		virtualNodes = append(virtualNodes, VirtualNodeEntry{
+			Score:         c.virtualNodeScore(nodeName, i),
+			VirtualNodeID: getVirtualNodeID(nodeName, i),
+		})
+	}
+	return virtualNodes
+}
+
+// 计算字符串在哈希环上的score
+func (c *ConsistentHash) score(origin string) int64 {
+	switch {
+	case c.ketama:
+		return ketamaHash(origin)
+	case c.encryptor64 != nil:
+		return int64(c.encryptor64.Encrypt64(origin))
+	default:
+		return int64(c.encryptor.Encrypt(origin))
+	}
+}
+
+// 计算nodeName的第index号虚拟节点的score，ketama模式下按ketama的方式计算，其他模式对虚拟节点id哈希
+func (c *ConsistentHash) virtualNodeScore(nodeName string, index int64) int64 {
+	if c.ketama {
+		return ketamaPoint(nodeName, index)
+	}
+	return c.score(getVirtualNodeID(nodeName, index))
+}
+
+// 哈希值位数，HashBits32或HashBits64
+func (c *ConsistentHash) hashBits() int {
+	if c.encryptor64 != nil {
+		return HashBits64
 	}
-	return nil
+	return HashBits32
 }
 
 func repairWeight(weight int64) int64 {
@@ -114,44 +240,265 @@ func (c *ConsistentHash) RemoveNode(ctx context.Context, nodeName string) error
 
 	defer c.hashRing.Unlock(ctx)
 
+	if err := c.saveSnapshot(ctx); err != nil {
+		return err
+	}
+	defer c.saveSnapshotAfterChange(ctx)
+
+	if c.ketama {
+		nodes, err := c.hashRing.GetRealNodes(ctx)
+		if err != nil {
+			return err
+		}
+		if err := c.beginOperation(ctx); err != nil {
+			return err
+		}
+		return c.rebalanceKetama(ctx, nodes, withKetamaWeight(nodes, nodeName, 0))
+	}
+
 	// 获取nodeName信息
 	replicas, err := c.hashRing.GetRealNode(ctx, nodeName)
 	if err != nil {
 		return err
 	}
+	if err := c.beginOperation(ctx); err != nil {
+		return err
+	}
+	virtualNodes := c.getVirtualNodeEntries(nodeName, 1, replicas)
+	// 删除之前计算出当前节点负责的区间以及删除后的新归属
+	arcs, err := c.getMigrationArcs(ctx, nil, virtualNodes)
+	if err != nil {
+		return err
+	}
 	//先删除ReadNode的信息
 	err = c.hashRing.RemoveRealNode(ctx, nodeName)
 	if err != nil {
 		return err
 	}
 
-	// 挨个删除
-	for i := int64(1); i <= replicas; i++ {
-		virtualNodeID := getVirtualNodeID(nodeName, i)
-		virtualNodeScore := c.encryptor.Encrypt(virtualNodeID)
-		if err := c.hashRing.RemoveVirtualNode(ctx, int64(virtualNodeScore), virtualNodeID); err != nil {
+	// 删除所有虚拟节点
+	if err := c.applyVirtualNodes(ctx, virtualNodes, false); err != nil {
+		return err
+	}
+
+	c.refreshCacheAfterChange(ctx)
+	return c.migrate(ctx, arcs)
+}
+
+// 原地修改节点权重，只增删新旧副本数之间的虚拟节点，并只迁移这些虚拟节点对应的区间
+func (c *ConsistentHash) UpdateNodeWeight(ctx context.Context, nodeName string, weight int64) error {
+	if err := c.hashRing.Lock(ctx, c.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer c.hashRing.Unlock(ctx)
+
+	if err := c.saveSnapshot(ctx); err != nil {
+		return err
+	}
+	defer c.saveSnapshotAfterChange(ctx)
+
+	replicas, err := c.hashRing.GetRealNode(ctx, nodeName)
+	if err != nil {
+		return err
+	}
+	if replicas <= 0 {
+		return ErrNodeNotExists
+	}
+	if c.ketama {
+		nodes, err := c.hashRing.GetRealNodes(ctx)
+		if err != nil {
 			return err
 		}
+		if err := c.beginOperation(ctx); err != nil {
+			return err
+		}
+		return c.rebalanceKetama(ctx, nodes, withKetamaWeight(nodes, nodeName, max(weight, 1)))
+	}
+	nodeReplicas := repairWeight(weight) * c.opts.replicas
+	if nodeReplicas == replicas {
+		return nil
+	}
+	if err := c.beginOperation(ctx); err != nil {
+		return err
 	}
 
-	return nil
+	// 权重变大，追加(replicas, nodeReplicas]号虚拟节点；权重变小，删除(nodeReplicas, replicas]号虚拟节点
+	var added, removed []VirtualNodeEntry
+	if nodeReplicas > replicas {
+		added = c.getVirtualNodeEntries(nodeName, replicas+1, nodeReplicas)
+	} else {
+		removed = c.getVirtualNodeEntries(nodeName, nodeReplicas+1, replicas)
+	}
+	arcs, err := c.getMigrationArcs(ctx, added, removed)
+	if err != nil {
+		return err
+	}
+	if err := c.hashRing.AddRealNode(ctx, nodeName, nodeReplicas); err != nil {
+		return err
+	}
+	if err := c.applyVirtualNodes(ctx, removed, false); err != nil {
+		return err
+	}
+	if err := c.applyVirtualNodes(ctx, added, true); err != nil {
+		return err
+	}
+	c.refreshCacheAfterChange(ctx)
+	return c.migrate(ctx, arcs)
+}
+
+// 获取真实节点列表，包含映射数量和节点状态
+func (c *ConsistentHash) ListNodes(ctx context.Context) (map[string]RealNode, error) {
+	return readRealNodeInfos(ctx, c.hashRing)
 }
 
+// 获取哈希环当前版本号
+func (c *ConsistentHash) Version(ctx context.Context) (int64, error) {
+	return c.hashRing.GetVersion(ctx)
+}
+
+// 查找数据所属的节点用于读取，跳过不可用、不健康的节点，开启有界负载模式时还会跳过负载已满的节点
+// 下线中的节点在其虚拟节点删除之前仍然负责原有数据，读取不跳过；写入新数据请使用GetNodeForWrite
+// 查找不加哈希环锁，哈希环的每次修改（包括批量修改）都是原子的并且只对应一个版本号，查找总是基于某个完整版本的哈希环
 func (c *ConsistentHash) GetNode(ctx context.Context, dataKey string) (nodeName string, err error) {
-	if err := c.hashRing.Lock(ctx, c.opts.lockExpireSeconds); err != nil {
+	return c.getNode(ctx, dataKey, false)
+}
+
+// 查找写入新数据的节点，与GetNode相比还会跳过下线中的节点
+func (c *ConsistentHash) GetNodeForWrite(ctx context.Context, dataKey string) (nodeName string, err error) {
+	return c.getNode(ctx, dataKey, true)
+}
+
+func (c *ConsistentHash) getNode(ctx context.Context, dataKey string, forWrite bool) (nodeName string, err error) {
+	dataScore := c.score(dataKey)
+	nodeName, err = c.lookup(ctx, dataScore, forWrite)
+	if err != nil {
 		return "", err
 	}
+	if c.opts.dataKeyStore != nil && c.opts.recordOnGetNode {
+		if err := c.opts.dataKeyStore.RegisterKey(ctx, dataKey, dataScore); err != nil {
+			return "", err
+		}
+	}
+	return nodeName, nil
+}
 
-	defer c.hashRing.Unlock(ctx)
+// 登记数据key，节点变更时会将其交给迁移回调，未配置数据key登记表时直接返回
+func (c *ConsistentHash) RegisterDataKey(ctx context.Context, dataKey string) error {
+	if c.opts.dataKeyStore == nil {
+		return nil
+	}
+	return c.opts.dataKeyStore.RegisterKey(ctx, dataKey, c.score(dataKey))
+}
 
-	dataScore := c.encryptor.Encrypt(dataKey)
-	virtualNodeID, err := c.hashRing.FindDataToVirtualNode(ctx, int64(dataScore))
+// 注销数据key，数据被删除后调用，未配置数据key登记表时直接返回
+func (c *ConsistentHash) UnregisterDataKey(ctx context.Context, dataKey string) error {
+	if c.opts.dataKeyStore == nil {
+		return nil
+	}
+	return c.opts.dataKeyStore.UnregisterKey(ctx, dataKey)
+}
+
+// 根据数据score找到对应的真实节点
+// 顺时针跳过不可用、不健康的节点，写入时还会跳过下线中的节点，开启有界负载模式时还会跳过负载已满的节点
+// 未开启本地缓存时，真实节点列表按哈希环版本号缓存，版本号不变时不再重新读取
+func (c *ConsistentHash) lookup(ctx context.Context, dataScore int64, forWrite bool) (string, error) {
+	nodes, err := c.getRealNodeInfos(ctx)
 	if err != nil {
 		return "", err
 	}
-	nodeName, _, err = parseVirtualNodeID(virtualNodeID)
+	// 所有节点都可用时，直接取顺时针的第一个节点
+	// 真实节点已经删除、剩余的虚拟节点还在哈希环上时（RemoveNode先删除真实节点），继续顺时针查找
+	if c.loads == nil && c.opts.healthChecker == nil && allUsable(nodes, forWrite) {
+		virtualNodeID, err := c.findDataToVirtualNode(ctx, dataScore)
+		if err != nil {
+			return "", err
+		}
+		nodeName, _, err := parseVirtualNodeID(virtualNodeID)
+		if err != nil {
+			return "", err
+		}
+		if nodes[nodeName].usable(forWrite) {
+			return nodeName, nil
+		}
+	}
+
+	totalReplicas := availableReplicas(nodes)
+	var firstAvailable, target string
+	err = c.walkNodes(ctx, dataScore, func(nodeName string) bool {
+		if !c.isAvailable(ctx, nodeName, nodes, forWrite) {
+			return true
+		}
+		if firstAvailable == "" {
+			firstAvailable = nodeName
+		}
+		if c.loads != nil && !c.loads.acceptable(nodeName, nodes[nodeName].Replicas, totalReplicas) {
+			return true
+		}
+		target = nodeName
+		return false
+	})
 	if err != nil {
 		return "", err
 	}
-	return nodeName, nil
+	// 所有可用节点都已满载时（只会在节点负载统计与哈希环不一致时出现），返回第一个可用节点
+	if target == "" {
+		target = firstAvailable
+	}
+	if target == "" {
+		return "", ErrNoAvailableNode
+	}
+	return target, nil
+}
+
+// 获取真实节点信息，开启本地缓存时从本地副本中获取，否则按哈希环版本号缓存
+func (c *ConsistentHash) getRealNodeInfos(ctx context.Context) (map[string]RealNode, error) {
+	if c.cache == nil {
+		return c.nodeInfos.get(ctx)
+	}
+	view, err := c.cache.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return view.nodes, nil
+}
+
+// 节点是否可以用于本次查找：状态满足读写要求并且健康检查通过
+func (c *ConsistentHash) isAvailable(ctx context.Context, nodeName string, nodes map[string]RealNode, forWrite bool) bool {
+	if !nodes[nodeName].usable(forWrite) {
+		return false
+	}
+	return c.opts.healthChecker == nil || c.opts.healthChecker.IsHealthy(ctx, nodeName)
+}
+
+func allUsable(nodes map[string]RealNode, forWrite bool) bool {
+	for _, node := range nodes {
+		if !node.usable(forWrite) {
+			return false
+		}
+	}
+	return true
+}
+
+// 所有可用节点的映射数量之和
+func availableReplicas(nodes map[string]RealNode) int64 {
+	totalReplicas := int64(0)
+	for _, node := range nodes {
+		if node.available() {
+			totalReplicas += node.Replicas
+		}
+	}
+	return totalReplicas
+}
+
+// 根据数据score找到对应的虚拟节点，开启本地缓存时在本地副本上查找
+func (c *ConsistentHash) findDataToVirtualNode(ctx context.Context, dataScore int64) (string, error) {
+	if c.cache == nil {
+		return c.hashRing.FindDataToVirtualNode(ctx, dataScore)
+	}
+	view, err := c.cache.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return view.findDataToVirtualNode(dataScore)
 }
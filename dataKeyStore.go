@@ -0,0 +1,69 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 10:12:37
+ */
+
+package csHash
+
+import (
+	"context"
+	"sync"
+)
+
+// 数据key登记表，记录每个数据key的score，节点变更时据此找出需要迁移的数据
+type DataKeyStore interface {
+	// 登记数据key，已存在则覆盖
+	RegisterKey(ctx context.Context, dataKey string, score int64) error
+	// 注销数据key，不存在直接返回
+	UnregisterKey(ctx context.Context, dataKey string) error
+	// 获取score处于(startScore, endScore]区间的数据key
+	// startScore >= endScore时，表示跨越哈希环首尾的区间(startScore, +inf) + (-inf, endScore]
+	ListKeys(ctx context.Context, startScore, endScore int64) (dataKeys map[string]struct{}, err error)
+}
+
+type memoryDataKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]int64
+}
+
+func NewMemoryDataKeyStore() *memoryDataKeyStore {
+	return &memoryDataKeyStore{
+		keys: make(map[string]int64),
+	}
+}
+
+func (m *memoryDataKeyStore) RegisterKey(ctx context.Context, dataKey string, score int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[dataKey] = score
+	return nil
+}
+
+func (m *memoryDataKeyStore) UnregisterKey(ctx context.Context, dataKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, dataKey)
+	return nil
+}
+
+func (m *memoryDataKeyStore) ListKeys(ctx context.Context, startScore, endScore int64) (map[string]struct{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	dataKeys := make(map[string]struct{})
+	for dataKey, score := range m.keys {
+		if InArc(score, startScore, endScore) {
+			dataKeys[dataKey] = struct{}{}
+		}
+	}
+	return dataKeys, nil
+}
+
+// score是否处于(startScore, endScore]区间，startScore >= endScore时区间跨越哈希环首尾
+// 与DataKeyStore.ListKeys的区间规则一致，供DataKeyStore的实现过滤数据key
+func InArc(score, startScore, endScore int64) bool {
+	if startScore < endScore {
+		return score > startScore && score <= endScore
+	}
+	return score > startScore || score <= endScore
+}
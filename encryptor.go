@@ -9,9 +9,19 @@ package csHash
 import (
 	"math"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/spaolacci/murmur3"
 )
 
+// 哈希值域大小，murmurHasher32的哈希值范围为 [0, hashSpace - 1]
+const hashSpace = math.MaxInt32
+
+// 哈希值位数，记录在RingSnapshot中，32位的值域为 [0, hashSpace - 1]，64位的值域为整个int64
+const (
+	HashBits32 = 32
+	HashBits64 = 64
+)
+
 type HashEncryptor interface {
 	Encrypt(origin string) int32
 }
@@ -28,3 +38,31 @@ func (m *murmurHasher32) Encrypt(origin string) int32 {
 	_, _ = hasher.Write([]byte(origin))
 	return int32(hasher.Sum32() % math.MaxInt32)
 }
+
+// 64位哈希，哈希值按补码转换为int64作为score，哈希环按int64升序排列
+// 首尾相接之后与按uint64排列只是起点不同，顺时针查找的结果一致
+type HashEncryptor64 interface {
+	Encrypt64(origin string) uint64
+}
+
+type murmurHasher128 struct{}
+
+func NewMurmurHasher128() *murmurHasher128 {
+	return &murmurHasher128{}
+}
+
+// 取murmur3-128的前64位
+func (m *murmurHasher128) Encrypt64(origin string) uint64 {
+	h1, _ := murmur3.Sum128([]byte(origin))
+	return h1
+}
+
+type xxHasher64 struct{}
+
+func NewXXHasher64() *xxHasher64 {
+	return &xxHasher64{}
+}
+
+func (x *xxHasher64) Encrypt64(origin string) uint64 {
+	return xxhash.Sum64String(origin)
+}
@@ -20,12 +20,67 @@ const (
 
 	ErrVirtualNodeNotExistsCode = 40002
 	ErrVirtualNodeNotExistsMsg  = "virtual node not exists"
+
+	ErrWatchNotSupportedCode = 40003
+	ErrWatchNotSupportedMsg  = "hash ring does not support watch"
+
+	ErrNodeNotExistsCode = 40004
+	ErrNodeNotExistsMsg  = "node not exists"
+
+	ErrNoAvailableNodeCode = 40005
+	ErrNoAvailableNodeMsg  = "no available node"
+
+	ErrRingChangingCode = 40006
+	ErrRingChangingMsg  = "hash ring keeps changing"
+
+	ErrSnapshotNotSupportedCode = 40007
+	ErrSnapshotNotSupportedMsg  = "hash ring does not support snapshot"
+
+	ErrSnapshotNotExistsCode = 40008
+	ErrSnapshotNotExistsMsg  = "snapshot not exists"
+
+	ErrRingNotSupportedCode = 40009
+	ErrRingNotSupportedMsg  = "hash ring does not support this operation"
+
+	ErrHistoryNotExistsCode = 40010
+	ErrHistoryNotExistsMsg  = "ring history not exists"
 )
 
 var ErrNodeAlreadyExists = newError(ErrNodeAlreadyExistsCode, errors.New(ErrNodeAlreadyExistsMsg))
 var ErrInvalidVirtualNodeID = newError(ErrInvalidVirtualNodeIDCode, errors.New(ErrInvalidVirtualNodeIDMsg))
 var ErrVirtualNodeNotExists = newError(ErrVirtualNodeNotExistsCode, errors.New(ErrVirtualNodeNotExistsMsg))
+var ErrWatchNotSupported = newError(ErrWatchNotSupportedCode, errors.New(ErrWatchNotSupportedMsg))
+var ErrNodeNotExists = newError(ErrNodeNotExistsCode, errors.New(ErrNodeNotExistsMsg))
+var ErrNoAvailableNode = newError(ErrNoAvailableNodeCode, errors.New(ErrNoAvailableNodeMsg))
+var ErrRingChanging = newError(ErrRingChangingCode, errors.New(ErrRingChangingMsg))
+var ErrSnapshotNotSupported = newError(ErrSnapshotNotSupportedCode, errors.New(ErrSnapshotNotSupportedMsg))
+var ErrSnapshotNotExists = newError(ErrSnapshotNotExistsCode, errors.New(ErrSnapshotNotExistsMsg))
+var ErrRingNotSupported = newError(ErrRingNotSupportedCode, errors.New(ErrRingNotSupportedMsg))
+var ErrHistoryNotExists = newError(ErrHistoryNotExistsCode, errors.New(ErrHistoryNotExistsMsg))
+
+// 带错误码的错误，错误码可以通过ErrorCode取出
+type Error struct {
+	Code int64
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("[err] code: %d  err: %s", e.Code, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
 
 func newError(code int64, err error) error {
-	return fmt.Errorf("[err] code: %d  err: %w", code, err)
+	return &Error{Code: code, Err: err}
+}
+
+// 取出错误链上的错误码，不是Error时返回0
+func ErrorCode(err error) int64 {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
 }
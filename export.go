@@ -0,0 +1,273 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 19:52:10
+ */
+
+package csHash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 导出文件格式
+//
+// JSON:
+//
+//	{
+//	  "version": 3,
+//	  "hash_bits": 32,
+//	  "real_nodes": {"node_a": {"replicas": 5, "state": "active"}},
+//	  "hash_scores": [
+//	    {"score": 1024, "virtual_nodes": [{"virtual_node_id": "node_a_1", "version": 1}]}
+//	  ]
+//	}
+//
+// YAML:
+//
+//	version: 3
+//	hash_bits: 32
+//	real_nodes:
+//	  node_a:
+//	    replicas: 5
+//	    state: active
+//	hash_scores:
+//	  - score: 1024
+//	    virtual_nodes:
+//	      - virtual_node_id: node_a_1
+//	        version: 1
+//
+// hash_bits为32或64，缺省时为32，ketama模式的哈希环还有"ketama": true，只能导入到哈希值位数和模式相同的ConsistentHash；
+// hash_scores按score升序排列，同一score上的虚拟节点按生效顺序排列，只有首个虚拟节点负责区间。
+// JSON与YAML的字段名保持一致，读取JSON时兼容旧版本导出的"VirtualNodeID"、"Version"
+type SnapshotFormat string
+
+const (
+	SnapshotFormatJSON SnapshotFormat = "json"
+	SnapshotFormatYAML SnapshotFormat = "yaml"
+)
+
+// 按指定格式写出快照
+func WriteRingSnapshot(w io.Writer, snapshot RingSnapshot, format SnapshotFormat) error {
+	switch format {
+	case SnapshotFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(snapshot)
+	case SnapshotFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(snapshot); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported snapshot format: %s", format)
+	}
+}
+
+// 按指定格式读取快照
+func ReadRingSnapshot(r io.Reader, format SnapshotFormat) (RingSnapshot, error) {
+	snapshot := RingSnapshot{}
+	var err error
+	switch format {
+	case SnapshotFormatJSON:
+		err = json.NewDecoder(r).Decode(&snapshot)
+	case SnapshotFormatYAML:
+		err = yaml.NewDecoder(r).Decode(&snapshot)
+	default:
+		err = fmt.Errorf("unsupported snapshot format: %s", format)
+	}
+	return snapshot, err
+}
+
+// 导出当前哈希环：真实节点、映射数量、节点状态、所有虚拟节点以及版本号
+// 不加哈希环锁，读取期间哈希环被修改时重新读取
+func (c *ConsistentHash) Export(ctx context.Context) (RingSnapshot, error) {
+	for i := 0; i < historyRetryTimes; i++ {
+		hashScores, version, err := c.getAllVirtualNodes(ctx)
+		if err != nil {
+			return RingSnapshot{}, err
+		}
+		nodes, err := readRealNodeInfos(ctx, c.hashRing)
+		if err != nil {
+			return RingSnapshot{}, err
+		}
+		latestVersion, err := c.hashRing.GetVersion(ctx)
+		if err != nil {
+			return RingSnapshot{}, err
+		}
+		if latestVersion == version {
+			return RingSnapshot{
+				Version:    version,
+				HashBits:   c.hashBits(),
+				Ketama:     c.ketama,
+				RealNodes:  nodes,
+				HashScores: hashScores,
+			}, nil
+		}
+	}
+	return RingSnapshot{}, ErrRingChanging
+}
+
+// 用导出的快照整体替换当前哈希环，用于备份恢复或在不同哈希环之间搬迁，不触发数据迁移
+// 导入后的版本号不小于快照中的版本号，并且大于导入前的版本号，保证本地缓存和订阅者能感知到变化
+// 哈希环实现了SnapshotHashRing时原子替换；否则逐个删除、添加，虚拟节点的版本号由哈希环重新分配
+// 快照中有非正常状态的节点而哈希环无法保存节点状态时，返回ErrRingNotSupported
+func (c *ConsistentHash) Import(ctx context.Context, snapshot RingSnapshot) error {
+	if err := validateSnapshot(&snapshot); err != nil {
+		return err
+	}
+	//score由不同位数的哈希计算，导入后无法正确查找
+	if snapshot.hashBits() != c.hashBits() {
+		return fmt.Errorf("invalid snapshot: hash bits %d, expect %d", snapshot.hashBits(), c.hashBits())
+	}
+	if snapshot.Ketama != c.ketama {
+		return fmt.Errorf("invalid snapshot: ketama %t, expect %t", snapshot.Ketama, c.ketama)
+	}
+	//替换之前检查，避免哈希环只被替换了一半
+	if !c.canImportStates(&snapshot) {
+		return ErrRingNotSupported
+	}
+	if err := c.hashRing.Lock(ctx, c.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer c.hashRing.Unlock(ctx)
+
+	if err := c.saveSnapshot(ctx); err != nil {
+		return err
+	}
+	defer c.saveSnapshotAfterChange(ctx)
+	defer c.refreshCacheAfterChange(ctx)
+
+	if err := c.beginOperation(ctx); err != nil {
+		return err
+	}
+	var version int64
+	var err error
+	if snapshotRing, ok := c.hashRing.(SnapshotHashRing); ok {
+		version, err = snapshotRing.ResetRing(ctx, &snapshot)
+	} else {
+		version, err = c.replaceRing(ctx, &snapshot)
+	}
+	if err != nil {
+		return err
+	}
+	if version < snapshot.Version {
+		return c.hashRing.SetVersion(ctx, snapshot.Version)
+	}
+	return nil
+}
+
+// 哈希环能否保存快照中的节点状态，快照中所有节点均为正常状态时不需要StatefulHashRing
+func (c *ConsistentHash) canImportStates(snapshot *RingSnapshot) bool {
+	switch c.hashRing.(type) {
+	case SnapshotHashRing, StatefulHashRing:
+		return true
+	}
+	for _, node := range snapshot.RealNodes {
+		if node.State != "" && node.State != NodeStateActive {
+			return false
+		}
+	}
+	return true
+}
+
+// 逐个删除当前哈希环的数据，再写入快照中的数据，返回写入后的版本号
+func (c *ConsistentHash) replaceRing(ctx context.Context, snapshot *RingSnapshot) (int64, error) {
+	hashScores, _, err := c.getAllVirtualNodes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.applyVirtualNodes(ctx, toVirtualNodeEntries(hashScores), false); err != nil {
+		return 0, err
+	}
+	nodes, err := c.hashRing.GetRealNodes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for nodeName := range nodes {
+		if err := c.hashRing.RemoveRealNode(ctx, nodeName); err != nil {
+			return 0, err
+		}
+	}
+
+	for nodeName, node := range snapshot.RealNodes {
+		if err := c.hashRing.AddRealNode(ctx, nodeName, node.Replicas); err != nil {
+			return 0, err
+		}
+		if node.State != "" && node.State != NodeStateActive {
+			if err := setRealNodeState(ctx, c.hashRing, nodeName, node.State); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := c.applyVirtualNodes(ctx, toVirtualNodeEntries(snapshot.HashScores), true); err != nil {
+		return 0, err
+	}
+	return c.hashRing.GetVersion(ctx)
+}
+
+// 批量添加或删除虚拟节点，哈希环不支持批量接口时逐个修改
+func (c *ConsistentHash) applyVirtualNodes(ctx context.Context, entries []VirtualNodeEntry, add bool) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if batchRing, ok := c.hashRing.(BatchHashRing); ok {
+		var err error
+		if add {
+			_, err = batchRing.AddVirtualNodes(ctx, entries)
+		} else {
+			_, err = batchRing.RemoveVirtualNodes(ctx, entries)
+		}
+		return err
+	}
+	for _, entry := range entries {
+		var err error
+		if add {
+			_, err = c.hashRing.AddVirtualNode(ctx, entry.Score, entry.VirtualNodeID)
+		} else {
+			err = c.hashRing.RemoveVirtualNode(ctx, entry.Score, entry.VirtualNodeID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按score和生效顺序展开所有虚拟节点
+func toVirtualNodeEntries(hashScores []*HashScore) []VirtualNodeEntry {
+	entries := make([]VirtualNodeEntry, 0, len(hashScores))
+	for _, hashScore := range hashScores {
+		for _, virtualNode := range hashScore.VirtualNodes {
+			entries = append(entries, VirtualNodeEntry{Score: hashScore.Score, VirtualNodeID: virtualNode.VirtualNodeID})
+		}
+	}
+	return entries
+}
+
+// 校验快照：虚拟节点id必须合法，并且所属的真实节点必须存在
+func validateSnapshot(snapshot *RingSnapshot) error {
+	for _, hashScore := range snapshot.HashScores {
+		if hashScore == nil {
+			return fmt.Errorf("invalid snapshot: empty hash score")
+		}
+		for _, virtualNode := range hashScore.VirtualNodes {
+			nodeName, _, err := parseVirtualNodeID(virtualNode.VirtualNodeID)
+			if err != nil {
+				return err
+			}
+			if _, ok := snapshot.RealNodes[nodeName]; !ok {
+				return fmt.Errorf("invalid snapshot: virtual node %s belongs to unknown node %s", virtualNode.VirtualNodeID, nodeName)
+			}
+		}
+	}
+	return nil
+}
@@ -3,15 +3,25 @@ module github.com/YShiJia/consistentHash
 go 1.22.5
 
 require (
+	github.com/alicebob/miniredis/v2 v2.39.0
+	github.com/cespare/xxhash/v2 v2.3.0
+	github.com/dchest/siphash v1.2.3
 	github.com/demdxx/gocast v1.2.0
 	github.com/gomodule/redigo v1.9.2
 	github.com/spaolacci/murmur3 v1.1.0
 	github.com/xiaoxuxiansheng/redis_lock v0.0.0-20230830022514-0a735ab2dd39
 	go.uber.org/zap v1.27.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240903143218-8af14fe29dc1
+	google.golang.org/grpc v1.68.1
+	google.golang.org/protobuf v1.35.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/YShiJia/skip_table v0.0.0-20240922140610-31e0674cd920 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/net v0.29.0 // indirect
+	golang.org/x/sys v0.25.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
 )
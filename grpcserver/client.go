@@ -0,0 +1,118 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 23:09:05
+ */
+
+package grpcserver
+
+import (
+	"context"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/grpcserver/pb"
+	"google.golang.org/grpc"
+)
+
+var _ csHash.Router = (*Client)(nil)
+
+// 远程哈希环客户端，查找接口与ConsistentHash一致，返回的错误同样可以用errors.Is判断
+type Client struct {
+	client pb.ConsistentHashClient
+}
+
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{client: pb.NewConsistentHashClient(conn)}
+}
+
+func (c *Client) GetNode(ctx context.Context, dataKey string) (nodeName string, err error) {
+	resp, err := c.client.Lookup(ctx, &pb.LookupRequest{Key: dataKey})
+	if err != nil {
+		return "", fromStatusError(err)
+	}
+	return resp.GetNode(), nil
+}
+
+func (c *Client) GetNodes(ctx context.Context, dataKey string, n int) (nodeNames []string, err error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+	resp, err := c.client.LookupN(ctx, &pb.LookupNRequest{Key: dataKey, N: int32(n)})
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	if resp.GetNodes() == nil {
+		return []string{}, nil
+	}
+	return resp.GetNodes(), nil
+}
+
+func (c *Client) AddNode(ctx context.Context, nodeName string, weight int64) error {
+	_, err := c.client.AddNode(ctx, &pb.AddNodeRequest{Name: nodeName, Weight: weight})
+	return fromStatusError(err)
+}
+
+func (c *Client) RemoveNode(ctx context.Context, nodeName string) error {
+	_, err := c.client.RemoveNode(ctx, &pb.RemoveNodeRequest{Name: nodeName})
+	return fromStatusError(err)
+}
+
+// 平滑下线节点，见ConsistentHash.DrainNode
+func (c *Client) DrainNode(ctx context.Context, nodeName string) error {
+	_, err := c.client.RemoveNode(ctx, &pb.RemoveNodeRequest{Name: nodeName, Drain: true})
+	return fromStatusError(err)
+}
+
+func (c *Client) ListNodes(ctx context.Context) (map[string]csHash.RealNode, error) {
+	resp, err := c.client.ListNodes(ctx, &pb.ListNodesRequest{})
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	nodes := make(map[string]csHash.RealNode, len(resp.GetNodes()))
+	for _, node := range resp.GetNodes() {
+		nodes[node.GetName()] = csHash.RealNode{
+			Replicas: node.GetReplicas(),
+			State:    csHash.NodeState(node.GetState()),
+		}
+	}
+	return nodes, nil
+}
+
+// 订阅哈希环变更事件，ctx结束或连接断开后关闭channel
+func (c *Client) Watch(ctx context.Context) (<-chan csHash.RingEvent, error) {
+	stream, err := c.client.WatchRing(ctx, &pb.WatchRingRequest{})
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	// 服务端订阅成功后才会发送header，订阅失败时没有header，错误需要通过Recv读取
+	header, err := stream.Header()
+	if err != nil {
+		return nil, fromStatusError(err)
+	}
+	if header == nil {
+		_, err := stream.Recv()
+		return nil, fromStatusError(err)
+	}
+
+	events := make(chan csHash.RingEvent)
+	go func() {
+		defer close(events)
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			select {
+			case events <- csHash.RingEvent{
+				Version:  event.GetVersion(),
+				Type:     csHash.RingEventType(event.GetType()),
+				NodeName: event.GetNodeName(),
+				State:    csHash.NodeState(event.GetState()),
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,89 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 22:50:19
+ */
+
+package grpcserver
+
+import (
+	"errors"
+	"strconv"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorInfo中的domain，metadata中的code为csHash中的错误码
+const errorDomain = "consistenthash"
+
+// 预定义错误对应的gRPC状态码，按errors.Is匹配，不同的错误可能共用同一个错误码，不能按错误码匹配
+// 客户端按错误码和错误信息还原为其中的预定义错误，保证errors.Is在远程调用时同样可用
+var statusCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{csHash.ErrNodeAlreadyExists, codes.AlreadyExists},
+	{csHash.ErrInvalidVirtualNodeID, codes.InvalidArgument},
+	{csHash.ErrVirtualNodeNotExists, codes.Unavailable},
+	{csHash.ErrWatchNotSupported, codes.Unimplemented},
+	{csHash.ErrNodeNotExists, codes.NotFound},
+	{csHash.ErrNoAvailableNode, codes.Unavailable},
+	{csHash.ErrRingChanging, codes.Unavailable},
+	{csHash.ErrSnapshotNotSupported, codes.Unimplemented},
+	{csHash.ErrSnapshotNotExists, codes.NotFound},
+	{csHash.ErrRingNotSupported, codes.Unimplemented},
+	{csHash.ErrHistoryNotExists, codes.NotFound},
+}
+
+// 将错误转换为gRPC状态，带错误码的错误通过ErrorInfo传递错误码
+func toStatusError(err error) error {
+	var e *csHash.Error
+	if !errors.As(err, &e) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	code := codes.Internal
+	for _, statusCode := range statusCodes {
+		if errors.Is(err, statusCode.err) {
+			code = statusCode.code
+			break
+		}
+	}
+	st, detailErr := status.New(code, e.Err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason:   e.Err.Error(),
+		Domain:   errorDomain,
+		Metadata: map[string]string{"code": strconv.FormatInt(e.Code, 10)},
+	})
+	if detailErr != nil {
+		return status.Error(code, e.Err.Error())
+	}
+	return st.Err()
+}
+
+// 将gRPC状态还原为带错误码的错误，没有错误码时原样返回
+func fromStatusError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != errorDomain {
+			continue
+		}
+		code, parseErr := strconv.ParseInt(info.Metadata["code"], 10, 64)
+		if parseErr != nil {
+			return err
+		}
+		for _, statusCode := range statusCodes {
+			var e *csHash.Error
+			if errors.As(statusCode.err, &e) && e.Code == code && e.Err.Error() == info.Reason {
+				return statusCode.err
+			}
+		}
+		return &csHash.Error{Code: code, Err: errors.New(info.Reason)}
+	}
+	return err
+}
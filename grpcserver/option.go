@@ -0,0 +1,30 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 14:20:51
+ */
+
+package grpcserver
+
+// 默认单次查找的最大节点数量
+const DefaultMaxN = 100
+
+type ServerOptions struct {
+	//单次查找的最大节点数量
+	maxN int32
+}
+
+type ServerOption func(opts *ServerOptions)
+
+// maxN LookupN中n的上限，超过时返回InvalidArgument
+func WithMaxN(maxN int32) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.maxN = maxN
+	}
+}
+
+func repairServer(opts *ServerOptions) {
+	if opts.maxN <= 0 {
+		opts.maxN = DefaultMaxN
+	}
+}
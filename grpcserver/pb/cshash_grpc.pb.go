@@ -0,0 +1,329 @@
+// 哈希环gRPC接口，由ConsistentHash实现，错误码见csHash中的错误码定义
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: cshash.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ConsistentHash_Lookup_FullMethodName     = "/cshash.v1.ConsistentHash/Lookup"
+	ConsistentHash_LookupN_FullMethodName    = "/cshash.v1.ConsistentHash/LookupN"
+	ConsistentHash_AddNode_FullMethodName    = "/cshash.v1.ConsistentHash/AddNode"
+	ConsistentHash_RemoveNode_FullMethodName = "/cshash.v1.ConsistentHash/RemoveNode"
+	ConsistentHash_ListNodes_FullMethodName  = "/cshash.v1.ConsistentHash/ListNodes"
+	ConsistentHash_WatchRing_FullMethodName  = "/cshash.v1.ConsistentHash/WatchRing"
+)
+
+// ConsistentHashClient is the client API for ConsistentHash service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ConsistentHashClient interface {
+	// 查找数据所属的节点
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	// 查找数据所属的n个不同的节点
+	LookupN(ctx context.Context, in *LookupNRequest, opts ...grpc.CallOption) (*LookupNResponse, error)
+	// 添加节点
+	AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error)
+	// 删除节点，drain为true时平滑下线
+	RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*RemoveNodeResponse, error)
+	// 真实节点列表
+	ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error)
+	// 订阅哈希环变更事件，哈希环需要支持订阅
+	WatchRing(ctx context.Context, in *WatchRingRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RingEvent], error)
+}
+
+type consistentHashClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConsistentHashClient(cc grpc.ClientConnInterface) ConsistentHashClient {
+	return &consistentHashClient{cc}
+}
+
+func (c *consistentHashClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupResponse)
+	err := c.cc.Invoke(ctx, ConsistentHash_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consistentHashClient) LookupN(ctx context.Context, in *LookupNRequest, opts ...grpc.CallOption) (*LookupNResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupNResponse)
+	err := c.cc.Invoke(ctx, ConsistentHash_LookupN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consistentHashClient) AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddNodeResponse)
+	err := c.cc.Invoke(ctx, ConsistentHash_AddNode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consistentHashClient) RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*RemoveNodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveNodeResponse)
+	err := c.cc.Invoke(ctx, ConsistentHash_RemoveNode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consistentHashClient) ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListNodesResponse)
+	err := c.cc.Invoke(ctx, ConsistentHash_ListNodes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consistentHashClient) WatchRing(ctx context.Context, in *WatchRingRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RingEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConsistentHash_ServiceDesc.Streams[0], ConsistentHash_WatchRing_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRingRequest, RingEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsistentHash_WatchRingClient = grpc.ServerStreamingClient[RingEvent]
+
+// ConsistentHashServer is the server API for ConsistentHash service.
+// All implementations must embed UnimplementedConsistentHashServer
+// for forward compatibility.
+type ConsistentHashServer interface {
+	// 查找数据所属的节点
+	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	// 查找数据所属的n个不同的节点
+	LookupN(context.Context, *LookupNRequest) (*LookupNResponse, error)
+	// 添加节点
+	AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error)
+	// 删除节点，drain为true时平滑下线
+	RemoveNode(context.Context, *RemoveNodeRequest) (*RemoveNodeResponse, error)
+	// 真实节点列表
+	ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error)
+	// 订阅哈希环变更事件，哈希环需要支持订阅
+	WatchRing(*WatchRingRequest, grpc.ServerStreamingServer[RingEvent]) error
+	mustEmbedUnimplementedConsistentHashServer()
+}
+
+// UnimplementedConsistentHashServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedConsistentHashServer struct{}
+
+func (UnimplementedConsistentHashServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedConsistentHashServer) LookupN(context.Context, *LookupNRequest) (*LookupNResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupN not implemented")
+}
+func (UnimplementedConsistentHashServer) AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddNode not implemented")
+}
+func (UnimplementedConsistentHashServer) RemoveNode(context.Context, *RemoveNodeRequest) (*RemoveNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveNode not implemented")
+}
+func (UnimplementedConsistentHashServer) ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNodes not implemented")
+}
+func (UnimplementedConsistentHashServer) WatchRing(*WatchRingRequest, grpc.ServerStreamingServer[RingEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRing not implemented")
+}
+func (UnimplementedConsistentHashServer) mustEmbedUnimplementedConsistentHashServer() {}
+func (UnimplementedConsistentHashServer) testEmbeddedByValue()                        {}
+
+// UnsafeConsistentHashServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConsistentHashServer will
+// result in compilation errors.
+type UnsafeConsistentHashServer interface {
+	mustEmbedUnimplementedConsistentHashServer()
+}
+
+func RegisterConsistentHashServer(s grpc.ServiceRegistrar, srv ConsistentHashServer) {
+	// If the following call pancis, it indicates UnimplementedConsistentHashServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ConsistentHash_ServiceDesc, srv)
+}
+
+func _ConsistentHash_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsistentHashServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsistentHash_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsistentHashServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsistentHash_LookupN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsistentHashServer).LookupN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsistentHash_LookupN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsistentHashServer).LookupN(ctx, req.(*LookupNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsistentHash_AddNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsistentHashServer).AddNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsistentHash_AddNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsistentHashServer).AddNode(ctx, req.(*AddNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsistentHash_RemoveNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsistentHashServer).RemoveNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsistentHash_RemoveNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsistentHashServer).RemoveNode(ctx, req.(*RemoveNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsistentHash_ListNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsistentHashServer).ListNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsistentHash_ListNodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsistentHashServer).ListNodes(ctx, req.(*ListNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsistentHash_WatchRing_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRingRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConsistentHashServer).WatchRing(m, &grpc.GenericServerStream[WatchRingRequest, RingEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsistentHash_WatchRingServer = grpc.ServerStreamingServer[RingEvent]
+
+// ConsistentHash_ServiceDesc is the grpc.ServiceDesc for ConsistentHash service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ConsistentHash_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cshash.v1.ConsistentHash",
+	HandlerType: (*ConsistentHashServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lookup",
+			Handler:    _ConsistentHash_Lookup_Handler,
+		},
+		{
+			MethodName: "LookupN",
+			Handler:    _ConsistentHash_LookupN_Handler,
+		},
+		{
+			MethodName: "AddNode",
+			Handler:    _ConsistentHash_AddNode_Handler,
+		},
+		{
+			MethodName: "RemoveNode",
+			Handler:    _ConsistentHash_RemoveNode_Handler,
+		},
+		{
+			MethodName: "ListNodes",
+			Handler:    _ConsistentHash_ListNodes_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRing",
+			Handler:       _ConsistentHash_WatchRing_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cshash.proto",
+}
@@ -0,0 +1,10 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 23:20:12
+ */
+
+// pb 由cshash.proto生成的gRPC代码
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cshash.proto
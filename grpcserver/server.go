@@ -0,0 +1,145 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 22:58:47
+ */
+
+// grpcserver 将ConsistentHash以gRPC接口的形式提供出去，并提供实现csHash.Router的客户端
+//
+//	grpcServer := grpc.NewServer()
+//	pb.RegisterConsistentHashServer(grpcServer, grpcserver.NewServer(ch))
+package grpcserver
+
+import (
+	"context"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/grpcserver/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	pb.UnimplementedConsistentHashServer
+	ch   *csHash.ConsistentHash
+	opts ServerOptions
+}
+
+func NewServer(ch *csHash.ConsistentHash, opts ...ServerOption) *Server {
+	s := Server{ch: ch}
+
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+
+	repairServer(&s.opts)
+	return &s
+}
+
+func (s *Server) Lookup(ctx context.Context, req *pb.LookupRequest) (*pb.LookupResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	nodeName, err := s.ch.GetNode(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.LookupResponse{Node: nodeName}, nil
+}
+
+func (s *Server) LookupN(ctx context.Context, req *pb.LookupNRequest) (*pb.LookupNResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	//n需要处于[1, maxN]，避免按调用方传入的n分配过大的内存
+	if req.GetN() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "n must be a positive integer")
+	}
+	if req.GetN() > s.opts.maxN {
+		return nil, status.Errorf(codes.InvalidArgument, "n is too large, max n is %d", s.opts.maxN)
+	}
+	nodeNames, err := s.ch.GetNodes(ctx, req.GetKey(), int(req.GetN()))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.LookupNResponse{Nodes: nodeNames}, nil
+}
+
+func (s *Server) AddNode(ctx context.Context, req *pb.AddNodeRequest) (*pb.AddNodeResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if err := s.ch.AddNode(ctx, req.GetName(), req.GetWeight()); err != nil {
+		return nil, toStatusError(err)
+	}
+	version, err := s.ch.Version(ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.AddNodeResponse{Version: version}, nil
+}
+
+func (s *Server) RemoveNode(ctx context.Context, req *pb.RemoveNodeRequest) (*pb.RemoveNodeResponse, error) {
+	nodes, err := s.ch.ListNodes(ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	if _, ok := nodes[req.GetName()]; !ok {
+		return nil, toStatusError(csHash.ErrNodeNotExists)
+	}
+
+	if req.GetDrain() {
+		err = s.ch.DrainNode(ctx, req.GetName())
+	} else {
+		err = s.ch.RemoveNode(ctx, req.GetName())
+	}
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	version, err := s.ch.Version(ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.RemoveNodeResponse{Version: version}, nil
+}
+
+func (s *Server) ListNodes(ctx context.Context, req *pb.ListNodesRequest) (*pb.ListNodesResponse, error) {
+	nodes, err := s.ch.ListNodes(ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	resp := pb.ListNodesResponse{Nodes: make([]*pb.Node, 0, len(nodes))}
+	for nodeName, node := range nodes {
+		resp.Nodes = append(resp.Nodes, &pb.Node{
+			Name:     nodeName,
+			Replicas: node.Replicas,
+			State:    string(node.State),
+		})
+	}
+	return &resp, nil
+}
+
+// 订阅哈希环变更事件，客户端断开或服务端停止时结束
+func (s *Server) WatchRing(req *pb.WatchRingRequest, stream grpc.ServerStreamingServer[pb.RingEvent]) error {
+	events, err := s.ch.Watch(stream.Context())
+	if err != nil {
+		return toStatusError(err)
+	}
+	// 订阅成功后立即发送header，客户端据此确认订阅已生效
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for event := range events {
+		err := stream.Send(&pb.RingEvent{
+			Version:  event.Version,
+			Type:     string(event.Type),
+			NodeName: event.NodeName,
+			State:    string(event.State),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
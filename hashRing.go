@@ -6,7 +6,10 @@
 
 package csHash
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+)
 
 // 这里的HashRing接口，有一个版本号控制，方便使用者辨别前一个hash版本的数据，和一个现hash版本的数据。
 // 每修改一次hash环，版本号加一
@@ -28,7 +31,7 @@ type HashRing interface {
 	// 根据数据score，找到对应的节点，顺时针向下查找
 	FindDataToVirtualNode(ctx context.Context, dataScore int64) (virtualNodeID string, err error)
 
-	// 设置真实节点列表，节点已存在，则报错
+	// 设置真实节点的映射数量，节点已存在时覆盖原有映射数量（实现了StatefulHashRing时保留节点状态，新节点状态为正常）
 	AddRealNode(ctx context.Context, nodeName string, replicas int64) (err error)
 	// 获取真实节点列表
 	GetRealNodes(ctx context.Context) (nodes map[string]int64, err error)
@@ -43,13 +46,77 @@ type HashRing interface {
 	SetVersion(ctx context.Context, version int64) (err error)
 }
 
+// 支持批量修改虚拟节点的哈希环，ConsistentHash会优先使用批量接口
+type BatchHashRing interface {
+	HashRing
+
+	// 批量添加虚拟节点，整批虚拟节点共用一个版本号，已存在的虚拟节点忽略，没有任何修改时返回当前版本号
+	AddVirtualNodes(ctx context.Context, virtualNodes []VirtualNodeEntry) (version int64, err error)
+	// 批量删除虚拟节点，整批只增加一次版本号，不存在的虚拟节点忽略，没有任何修改时返回当前版本号
+	RemoveVirtualNodes(ctx context.Context, virtualNodes []VirtualNodeEntry) (version int64, err error)
+}
+
+// 支持一次读取整个哈希环的哈希环，迁移区间计算、本地缓存、快照和导出会优先使用
+// 未实现时ConsistentHash根据真实节点列表重新计算所有虚拟节点，只有多个虚拟节点落在同一score上时才读取该score
+type ListableHashRing interface {
+	HashRing
+
+	// 获取整个哈希环上的数据（按score升序）以及对应的版本号，两者需要原子读取
+	GetAllVirtualNodes(ctx context.Context) (hashScores []*HashScore, version int64, err error)
+}
+
+// 支持按score分页读取的哈希环，未开启本地缓存时GetNodes等顺时针遍历会优先使用
+// 未实现时每次遍历读取整个哈希环
+type RangeHashRing interface {
+	HashRing
+
+	// 顺时针获取score大于等于startScore的数据（按score升序），最多limit个，到达环尾即停止，不回到环首
+	RangeVirtualNodes(ctx context.Context, startScore int64, limit int64) (hashScores []*HashScore, err error)
+}
+
+// 支持保存真实节点状态的哈希环，SetNodeState、DrainNode依赖该接口
+// 未实现时所有真实节点视为正常，修改节点状态返回ErrRingNotSupported
+type StatefulHashRing interface {
+	HashRing
+
+	// 获取真实节点列表，包含映射数量和节点状态
+	GetRealNodeInfos(ctx context.Context) (nodes map[string]RealNode, err error)
+	// 修改真实节点状态，版本号加一，节点不存在则报错
+	SetRealNodeState(ctx context.Context, nodeName string, state NodeState) (err error)
+}
+
+// 虚拟节点及其所在的score
+type VirtualNodeEntry struct {
+	Score         int64  `json:"score"`
+	VirtualNodeID string `json:"virtual_node_id"`
+}
+
 type VirtualNode struct {
-	VirtualNodeID string
-	Version       int64
+	VirtualNodeID string `json:"virtual_node_id" yaml:"virtual_node_id"`
+	Version       int64  `json:"version" yaml:"version"`
+}
+
+// 兼容字段名为VirtualNodeID、Version的旧数据，Redis哈希环中已有的虚拟节点和旧的JSON快照都是这种格式
+func (v *VirtualNode) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		VirtualNodeID       string `json:"virtual_node_id"`
+		LegacyVirtualNodeID string `json:"VirtualNodeID"`
+		// 字段名匹配不区分大小写，旧数据中的Version也会解析到这里
+		Version int64 `json:"version"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	v.VirtualNodeID = raw.VirtualNodeID
+	if v.VirtualNodeID == "" {
+		v.VirtualNodeID = raw.LegacyVirtualNodeID
+	}
+	v.Version = raw.Version
+	return nil
 }
 
 type HashScore struct {
-	Score        int64         `json:"score"`
-	VirtualNodes []VirtualNode `json:"virtual_nodes"`
+	Score        int64         `json:"score" yaml:"score"`
+	VirtualNodes []VirtualNode `json:"virtual_nodes" yaml:"virtual_nodes"`
 	//version      int64            `json:"version"` //所有节点中最新的版本号，供以后使用
 }
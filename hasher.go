@@ -0,0 +1,174 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 14:26:51
+ */
+
+package csHash
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/dchest/siphash"
+)
+
+// 可以按名称创建的哈希算法
+const (
+	HashMurmur3    = "murmur3"
+	HashFNV1a      = "fnv1a"
+	HashCRC32C     = "crc32c"
+	HashXXHash     = "xxhash"
+	HashSipHash    = "siphash"
+	HashSHA1       = "sha1"
+	HashMD5        = "md5"
+	HashMurmur3128 = "murmur3-128"
+	HashXXHash64   = "xxhash64"
+)
+
+var hashEncryptors = map[string]func(opts *EncryptorOptions) HashEncryptor{
+	HashMurmur3: func(*EncryptorOptions) HashEncryptor { return NewMurmurHasher32() },
+	HashFNV1a:   func(*EncryptorOptions) HashEncryptor { return NewFNV1aHasher() },
+	HashCRC32C:  func(*EncryptorOptions) HashEncryptor { return NewCRC32CHasher() },
+	HashXXHash:  func(*EncryptorOptions) HashEncryptor { return NewXXHasher32() },
+	HashSipHash: func(opts *EncryptorOptions) HashEncryptor { return NewSipHasher(opts.sipHashKey) },
+	HashSHA1:    func(*EncryptorOptions) HashEncryptor { return NewSHA1Hasher() },
+	HashMD5:     func(*EncryptorOptions) HashEncryptor { return NewMD5Hasher() },
+}
+
+var hashEncryptors64 = map[string]func(opts *EncryptorOptions) HashEncryptor64{
+	HashMurmur3128: func(*EncryptorOptions) HashEncryptor64 { return NewMurmurHasher128() },
+	HashXXHash64:   func(*EncryptorOptions) HashEncryptor64 { return NewXXHasher64() },
+}
+
+// 按名称创建32位哈希，名称见HashMurmur3等常量
+func NewHashEncryptor(name string, opts ...EncryptorOption) (HashEncryptor, error) {
+	newEncryptor, ok := hashEncryptors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash encryptor: %s", name)
+	}
+	return newEncryptor(newEncryptorOptions(opts)), nil
+}
+
+// 按名称创建64位哈希，名称见HashMurmur3128、HashXXHash64
+func NewHashEncryptor64(name string, opts ...EncryptorOption) (HashEncryptor64, error) {
+	newEncryptor, ok := hashEncryptors64[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash encryptor: %s", name)
+	}
+	return newEncryptor(newEncryptorOptions(opts)), nil
+}
+
+// 所有可以按名称创建的32位哈希，按名称排序
+func HashEncryptorNames() []string {
+	return sortedNames(hashEncryptors)
+}
+
+// 所有可以按名称创建的64位哈希，按名称排序
+func HashEncryptor64Names() []string {
+	return sortedNames(hashEncryptors64)
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newEncryptorOptions(opts []EncryptorOption) *EncryptorOptions {
+	options := &EncryptorOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// 与murmurHasher32相同，把哈希值折叠到 [0, 1<<31 - 2]
+func fold32(sum uint64) int32 {
+	return int32(sum % math.MaxInt32)
+}
+
+type fnv1aHasher struct{}
+
+func NewFNV1aHasher() *fnv1aHasher {
+	return &fnv1aHasher{}
+}
+
+func (f *fnv1aHasher) Encrypt(origin string) int32 {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(origin))
+	return fold32(uint64(hasher.Sum32()))
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+type crc32cHasher struct{}
+
+func NewCRC32CHasher() *crc32cHasher {
+	return &crc32cHasher{}
+}
+
+func (c *crc32cHasher) Encrypt(origin string) int32 {
+	return fold32(uint64(crc32.Checksum([]byte(origin), castagnoliTable)))
+}
+
+type xxHasher32 struct{}
+
+// xxHash64折叠到32位的值域
+func NewXXHasher32() *xxHasher32 {
+	return &xxHasher32{}
+}
+
+func (x *xxHasher32) Encrypt(origin string) int32 {
+	return fold32(xxhash.Sum64String(origin))
+}
+
+type sipHasher struct {
+	k0, k1 uint64
+}
+
+// SipHash-2-4，key按小端序拆成两个uint64，不同key得到的哈希环互不相同
+func NewSipHasher(key [16]byte) *sipHasher {
+	return &sipHasher{
+		k0: binary.LittleEndian.Uint64(key[:8]),
+		k1: binary.LittleEndian.Uint64(key[8:]),
+	}
+}
+
+func (s *sipHasher) Encrypt(origin string) int32 {
+	return fold32(siphash.Hash(s.k0, s.k1, []byte(origin)))
+}
+
+// SHA-1和MD5取摘要前4个字节按小端序组成的uint32，再与其他哈希一样折叠到 [0, 1<<31 - 2]
+// 折叠后与libketama的score不同，需要与libketama兼容时使用NewKetamaConsistentHash（见ketama.go）
+type sha1Hasher struct{}
+
+func NewSHA1Hasher() *sha1Hasher {
+	return &sha1Hasher{}
+}
+
+func (s *sha1Hasher) Encrypt(origin string) int32 {
+	digest := sha1.Sum([]byte(origin))
+	return fold32(uint64(binary.LittleEndian.Uint32(digest[:4])))
+}
+
+type md5Hasher struct{}
+
+func NewMD5Hasher() *md5Hasher {
+	return &md5Hasher{}
+}
+
+func (m *md5Hasher) Encrypt(origin string) int32 {
+	digest := md5.Sum([]byte(origin))
+	return fold32(uint64(binary.LittleEndian.Uint32(digest[:4])))
+}
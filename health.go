@@ -0,0 +1,197 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 17:25:03
+ */
+
+package csHash
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 节点健康检查，查找时跳过不健康的节点，但不修改哈希环
+type HealthChecker interface {
+	// 节点是否健康，需要快速返回，不应在查找路径上发起探测
+	IsHealthy(ctx context.Context, nodeName string) bool
+}
+
+// 探测一个节点，返回nil表示健康
+type Prober func(ctx context.Context, nodeName string) error
+
+// TCP探测，节点名即为节点地址（host:port）
+func TCPProber() Prober {
+	return func(ctx context.Context, nodeName string) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", nodeName)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTP探测，请求fmt.Sprintf(urlFormat, nodeName)，例如 "http://%s/health"，返回2xx视为健康
+func HTTPProber(urlFormat string) Prober {
+	return func(ctx context.Context, nodeName string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(urlFormat, nodeName), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("unhealthy status code: %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+type nodeHealth struct {
+	// 探测结果
+	healthy bool
+	// 连续探测失败次数
+	failures int64
+	// 被手动标记为不健康
+	markedDown bool
+}
+
+// 基于探测的健康检查，后台定期探测查找过程中遇到过的节点，并支持手动标记节点不健康
+// 新节点在第一次探测之前视为健康
+type ProbeHealthChecker struct {
+	probe  Prober
+	opts   HealthCheckerOptions
+	mu     sync.RWMutex
+	nodes  map[string]*nodeHealth
+	cancel context.CancelFunc
+}
+
+// probe为nil时不做探测，只使用手动标记
+func NewProbeHealthChecker(probe Prober, opts ...HealthCheckerOption) *ProbeHealthChecker {
+	h := ProbeHealthChecker{
+		probe: probe,
+		nodes: make(map[string]*nodeHealth),
+	}
+
+	for _, opt := range opts {
+		opt(&h.opts)
+	}
+
+	h.opts.repair()
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	if probe != nil {
+		go h.run(ctx)
+	}
+	return &h
+}
+
+// 只使用手动标记的健康检查
+func NewManualHealthChecker() *ProbeHealthChecker {
+	return NewProbeHealthChecker(nil)
+}
+
+func (h *ProbeHealthChecker) IsHealthy(ctx context.Context, nodeName string) bool {
+	//探测协程会在写锁下修改nodeHealth，需要在读锁内读取
+	h.mu.RLock()
+	health, ok := h.nodes[nodeName]
+	healthy := ok && health.healthy && !health.markedDown
+	h.mu.RUnlock()
+	if ok {
+		return healthy
+	}
+
+	//第一次遇到的节点，加入探测列表
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.nodes[nodeName]; !ok {
+		h.nodes[nodeName] = &nodeHealth{healthy: true}
+	}
+	return true
+}
+
+// 手动标记节点不健康，直到调用MarkUp
+func (h *ProbeHealthChecker) MarkDown(nodeName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.getOrCreate(nodeName).markedDown = true
+}
+
+// 取消手动标记，节点是否健康重新由探测结果决定
+func (h *ProbeHealthChecker) MarkUp(nodeName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.getOrCreate(nodeName).markedDown = false
+}
+
+// 停止后台探测
+func (h *ProbeHealthChecker) Close() {
+	h.cancel()
+}
+
+// 调用方需持有h.mu
+func (h *ProbeHealthChecker) getOrCreate(nodeName string) *nodeHealth {
+	health, ok := h.nodes[nodeName]
+	if !ok {
+		health = &nodeHealth{healthy: true}
+		h.nodes[nodeName] = health
+	}
+	return health
+}
+
+func (h *ProbeHealthChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(h.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		h.probeAll(ctx)
+	}
+}
+
+// 并发探测所有已知节点，连续失败达到阈值后视为不健康，一次成功即恢复
+func (h *ProbeHealthChecker) probeAll(ctx context.Context) {
+	h.mu.RLock()
+	nodeNames := make([]string, 0, len(h.nodes))
+	for nodeName := range h.nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	h.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, nodeName := range nodeNames {
+		wg.Add(1)
+		go func(nodeName string) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, h.opts.timeout)
+			defer cancel()
+			err := h.probe(probeCtx, nodeName)
+
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			health := h.getOrCreate(nodeName)
+			if err == nil {
+				health.failures = 0
+				health.healthy = true
+				return
+			}
+			health.failures++
+			if health.failures >= h.opts.failureThreshold {
+				health.healthy = false
+			}
+		}(nodeName)
+	}
+	wg.Wait()
+}
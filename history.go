@@ -0,0 +1,199 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 18:02:47
+ */
+
+package csHash
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// 读取上一版本归属时，哈希环持续被修改的最大重试次数
+const historyRetryTimes = 3
+
+// 获取数据当前所属的节点，以及最近一次逻辑操作开始之前数据所属的节点，迁移进行中时读路径可以回退到旧节点
+// 逻辑操作指AddNode、RemoveNode、UpdateNodeWeight、SetNodeState（包括之后逐个删除虚拟节点的下线过程）、Rollback和Import，
+// 一次操作可能包含多次修改，上一版本指整个操作开始之前的哈希环。
+// 当前节点与GetNode的结果相同；上一版本的节点只根据上一版本的虚拟节点计算，不考虑节点状态、健康检查和负载，
+// 即数据在上一版本中实际存放的位置。上一版本哈希环为空时previousNodeName为空字符串，
+// 哈希环没有修改记录或这次操作只改变了节点状态时，两个版本的虚拟节点相同；
+// 操作中包含回滚时，需要开启快照才能读取上一版本。哈希环未实现ChangeLogHashRing时返回ErrRingNotSupported，
+// 操作包含的修改超过MaxOperationChanges时返回ErrHistoryNotExists
+func (c *ConsistentHash) GetNodeWithHistory(ctx context.Context, dataKey string) (nodeName, previousNodeName string, err error) {
+	changeLogRing, ok := c.hashRing.(ChangeLogHashRing)
+	if !ok {
+		return "", "", ErrRingNotSupported
+	}
+	nodeName, err = c.GetNode(ctx, dataKey)
+	if err != nil {
+		return "", "", err
+	}
+	previousNodeName, err = c.getPreviousNode(ctx, changeLogRing, c.score(dataKey))
+	if err != nil {
+		return "", "", err
+	}
+	return nodeName, previousNodeName, nil
+}
+
+// 开始一次新的逻辑操作，哈希环未实现ChangeLogHashRing时不记录，调用方需持有哈希环的锁
+func (c *ConsistentHash) beginOperation(ctx context.Context) error {
+	if changeLogRing, ok := c.hashRing.(ChangeLogHashRing); ok {
+		return changeLogRing.BeginOperation(ctx)
+	}
+	return nil
+}
+
+// 根据最近一次逻辑操作的全部修改，还原操作开始之前哈希环中dataScore所属的真实节点
+// 读取前后版本号不一致说明期间哈希环被修改过，重新读取
+func (c *ConsistentHash) getPreviousNode(ctx context.Context, changeLogRing ChangeLogHashRing, dataScore int64) (string, error) {
+	for i := 0; i < historyRetryTimes; i++ {
+		version, err := c.hashRing.GetVersion(ctx)
+		if err != nil {
+			return "", err
+		}
+		operation, err := changeLogRing.GetLastOperation(ctx)
+		if err != nil {
+			return "", err
+		}
+		changes := operation.Changes
+		//修改记录不属于当前版本（例如直接调用过SetVersion），无法还原上一版本
+		if len(changes) > 0 && changes[len(changes)-1].Version != version {
+			changes = nil
+		}
+		//最早的修改记录已经被丢弃
+		if len(changes) > 0 && changes[0].Version != operation.StartVersion+1 {
+			return "", ErrHistoryNotExists
+		}
+
+		var nodeName string
+		if hasResetChange(changes) {
+			nodeName, err = c.findNodeInSnapshot(ctx, dataScore, operation.StartVersion)
+		} else {
+			added, removed := netVirtualNodeChanges(changes)
+			nodeName, err = c.findNodeBefore(ctx, dataScore, added, removed)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		latestVersion, err := c.hashRing.GetVersion(ctx)
+		if err != nil {
+			return "", err
+		}
+		if latestVersion == version {
+			return nodeName, nil
+		}
+	}
+	return "", ErrRingChanging
+}
+
+func hasResetChange(changes []*RingChange) bool {
+	for _, change := range changes {
+		if change.Type == RingChangeReset {
+			return true
+		}
+	}
+	return false
+}
+
+// 合并一次操作中的多次修改，得到操作前后虚拟节点的净增加和净删除，先加后删的虚拟节点相互抵消
+func netVirtualNodeChanges(changes []*RingChange) (added, removed []VirtualNodeEntry) {
+	addedSet := make(map[VirtualNodeEntry]struct{})
+	removedSet := make(map[VirtualNodeEntry]struct{})
+	for _, change := range changes {
+		for _, entry := range change.VirtualNodes {
+			switch change.Type {
+			case RingChangeAdd:
+				if _, ok := removedSet[entry]; ok {
+					delete(removedSet, entry)
+				} else {
+					addedSet[entry] = struct{}{}
+				}
+			case RingChangeRemove:
+				if _, ok := addedSet[entry]; ok {
+					delete(addedSet, entry)
+				} else {
+					removedSet[entry] = struct{}{}
+				}
+			}
+		}
+	}
+	for entry := range addedSet {
+		added = append(added, entry)
+	}
+	for entry := range removedSet {
+		removed = append(removed, entry)
+	}
+	//同一score上加回多个虚拟节点时结果保持稳定
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].Score != removed[j].Score {
+			return removed[i].Score < removed[j].Score
+		}
+		return removed[i].VirtualNodeID < removed[j].VirtualNodeID
+	})
+	return added, removed
+}
+
+// 整个哈希环被替换过时，从上一版本的快照中查找dataScore所属的真实节点
+func (c *ConsistentHash) findNodeInSnapshot(ctx context.Context, dataScore int64, version int64) (string, error) {
+	snapshot, err := c.GetSnapshot(ctx, version)
+	if err != nil {
+		return "", err
+	}
+	virtualNodeID, err := newRingView(snapshot.Version, snapshot.HashScores, nil).findDataToVirtualNode(dataScore)
+	if err != nil {
+		if errors.Is(err, ErrVirtualNodeNotExists) {
+			return "", nil
+		}
+		return "", err
+	}
+	nodeName, _, err := parseVirtualNodeID(virtualNodeID)
+	return nodeName, err
+}
+
+// 在当前哈希环中去掉excluded、加回included之后，顺时针查找dataScore所属的真实节点，哈希环为空时返回空字符串
+// 加回的虚拟节点与现有虚拟节点在同一score上时，无法得知原先的先后顺序，以现有虚拟节点为准
+func (c *ConsistentHash) findNodeBefore(ctx context.Context, dataScore int64, excluded, included []VirtualNodeEntry) (string, error) {
+	excludedSet := make(map[VirtualNodeEntry]struct{}, len(excluded))
+	for _, entry := range excluded {
+		excludedSet[entry] = struct{}{}
+	}
+
+	virtualNodeID := ""
+	var distance uint64
+	err := c.walkHashScores(ctx, dataScore, false, func(hashScore *HashScore) (bool, error) {
+		//同一score上的虚拟节点按顺序生效，排在前面的被去掉后由下一个接管
+		for _, virtualNode := range hashScore.VirtualNodes {
+			if _, ok := excludedSet[VirtualNodeEntry{Score: hashScore.Score, VirtualNodeID: virtualNode.VirtualNodeID}]; !ok {
+				virtualNodeID = virtualNode.VirtualNodeID
+				distance = clockwiseDistance(dataScore, hashScore.Score)
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil && !errors.Is(err, ErrVirtualNodeNotExists) {
+		return "", err
+	}
+
+	for _, entry := range included {
+		if d := clockwiseDistance(dataScore, entry.Score); virtualNodeID == "" || d < distance {
+			virtualNodeID = entry.VirtualNodeID
+			distance = d
+		}
+	}
+	if virtualNodeID == "" {
+		return "", nil
+	}
+	nodeName, _, err := parseVirtualNodeID(virtualNodeID)
+	return nodeName, err
+}
+
+// 从from顺时针走到to的距离，利用无符号整数溢出处理回绕
+func clockwiseDistance(from, to int64) uint64 {
+	return uint64(to - from)
+}
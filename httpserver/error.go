@@ -0,0 +1,79 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 21:49:33
+ */
+
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	csHash "github.com/YShiJia/consistentHash"
+)
+
+const (
+	// 请求参数错误
+	ErrBadRequestCode = 40000
+	// 服务内部错误，没有对应错误码的错误统一使用该错误码
+	ErrInternalCode = 50000
+)
+
+// 错误响应
+type ErrorResponse struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
+
+// 预定义错误对应的http状态码，按errors.Is匹配，不同的错误可能共用同一个错误码，不能按错误码匹配
+var statusCodes = []struct {
+	err    error
+	status int
+}{
+	{csHash.ErrNodeAlreadyExists, http.StatusConflict},
+	{csHash.ErrInvalidVirtualNodeID, http.StatusBadRequest},
+	{csHash.ErrVirtualNodeNotExists, http.StatusServiceUnavailable},
+	{csHash.ErrWatchNotSupported, http.StatusNotImplemented},
+	{csHash.ErrNodeNotExists, http.StatusNotFound},
+	{csHash.ErrNoAvailableNode, http.StatusServiceUnavailable},
+	{csHash.ErrRingChanging, http.StatusServiceUnavailable},
+	{csHash.ErrSnapshotNotSupported, http.StatusNotImplemented},
+	{csHash.ErrSnapshotNotExists, http.StatusNotFound},
+	{csHash.ErrRingNotSupported, http.StatusNotImplemented},
+	{csHash.ErrHistoryNotExists, http.StatusNotFound},
+}
+
+// 请求参数错误
+type badRequestError struct {
+	message string
+}
+
+func (e *badRequestError) Error() string {
+	return e.message
+}
+
+func newBadRequestError(message string) error {
+	return &badRequestError{message: message}
+}
+
+// 将错误转换为http状态码和错误响应，错误信息不包含错误码前缀
+func toErrorResponse(err error) (int, ErrorResponse) {
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		return http.StatusBadRequest, ErrorResponse{Code: ErrBadRequestCode, Message: badRequest.message}
+	}
+
+	var e *csHash.Error
+	if !errors.As(err, &e) {
+		return http.StatusInternalServerError, ErrorResponse{Code: ErrInternalCode, Message: err.Error()}
+	}
+	status := http.StatusInternalServerError
+	for _, statusCode := range statusCodes {
+		if errors.Is(err, statusCode.err) {
+			status = statusCode.status
+			break
+		}
+	}
+	return status, ErrorResponse{Code: e.Code, Message: e.Err.Error()}
+}
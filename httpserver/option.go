@@ -0,0 +1,67 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 21:46:12
+ */
+
+package httpserver
+
+const (
+	// 默认批量查找的最大key数量
+	DefaultMaxBatchSize = 1000
+	// 默认请求体大小上限
+	DefaultMaxBodyBytes = 1 << 20
+	// 默认单次查找的最大节点数量
+	DefaultMaxN = 100
+)
+
+type ServerOptions struct {
+	//批量查找的最大key数量
+	maxBatchSize int
+	//请求体大小上限
+	maxBodyBytes int64
+	//单次查找的最大节点数量
+	maxN int
+	//路由前缀
+	prefix string
+}
+
+type ServerOption func(opts *ServerOptions)
+
+func WithMaxBatchSize(maxBatchSize int) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.maxBatchSize = maxBatchSize
+	}
+}
+
+func WithMaxBodyBytes(maxBodyBytes int64) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.maxBodyBytes = maxBodyBytes
+	}
+}
+
+// maxN 查找接口中n的上限，超过时返回参数错误
+func WithMaxN(maxN int) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.maxN = maxN
+	}
+}
+
+// prefix 所有路由的前缀，例如 "/cshash"
+func WithPrefix(prefix string) ServerOption {
+	return func(opts *ServerOptions) {
+		opts.prefix = prefix
+	}
+}
+
+func repairServer(opts *ServerOptions) {
+	if opts.maxBatchSize <= 0 {
+		opts.maxBatchSize = DefaultMaxBatchSize
+	}
+	if opts.maxBodyBytes <= 0 {
+		opts.maxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.maxN <= 0 {
+		opts.maxN = DefaultMaxN
+	}
+}
@@ -0,0 +1,329 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 21:55:08
+ */
+
+// httpserver 将ConsistentHash以REST接口的形式提供给非Go服务使用
+//
+//	GET    /v1/lookup?key=k[&n=2]        查找数据所属的节点，n > 1 时返回n个不同的节点，n不能超过WithMaxN
+//	POST   /v1/lookup                    批量查找 {"keys": ["k1", "k2"], "n": 1}，n省略时为1
+//	GET    /v1/nodes                     真实节点列表
+//	POST   /v1/nodes                     添加节点 {"name": "node_a", "weight": 1}
+//	DELETE /v1/nodes/{name}[?drain=true] 删除节点，drain为true时平滑下线
+//	PUT    /v1/nodes/{name}/weight       修改节点权重 {"weight": 2}
+//	GET    /v1/ring[?format=yaml]        导出整个哈希环，格式见csHash.RingSnapshot
+//	GET    /v1/version                   哈希环版本号
+//	GET    /v1/stats                     分布统计，格式见csHash.RingStats
+//
+// 出错时返回 {"code": 40001, "message": "node already exists"}，code为csHash中的错误码
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	csHash "github.com/YShiJia/consistentHash"
+)
+
+type Server struct {
+	ch   *csHash.ConsistentHash
+	opts ServerOptions
+	mux  *http.ServeMux
+}
+
+func NewServer(ch *csHash.ConsistentHash, opts ...ServerOption) *Server {
+	s := Server{
+		ch:  ch,
+		mux: http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+
+	repairServer(&s.opts)
+	s.route()
+	return &s
+}
+
+func (s *Server) route() {
+	prefix := s.opts.prefix
+	s.mux.HandleFunc("GET "+prefix+"/v1/lookup", s.lookup)
+	s.mux.HandleFunc("POST "+prefix+"/v1/lookup", s.batchLookup)
+	s.mux.HandleFunc("GET "+prefix+"/v1/nodes", s.listNodes)
+	s.mux.HandleFunc("POST "+prefix+"/v1/nodes", s.addNode)
+	s.mux.HandleFunc("DELETE "+prefix+"/v1/nodes/{name}", s.removeNode)
+	s.mux.HandleFunc("PUT "+prefix+"/v1/nodes/{name}/weight", s.updateNodeWeight)
+	s.mux.HandleFunc("GET "+prefix+"/v1/ring", s.dump)
+	s.mux.HandleFunc("GET "+prefix+"/v1/version", s.version)
+	s.mux.HandleFunc("GET "+prefix+"/v1/stats", s.stats)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type LookupResult struct {
+	Key   string   `json:"key"`
+	Nodes []string `json:"nodes"`
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, newBadRequestError("key is required"))
+		return
+	}
+	n, err := s.parseN(r.URL.Query().Get("n"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := s.lookupKey(r, key, n)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+type BatchLookupRequest struct {
+	Keys []string `json:"keys"`
+	N    int      `json:"n"`
+}
+
+type BatchLookupResponse struct {
+	Results []LookupResult `json:"results"`
+}
+
+func (s *Server) batchLookup(w http.ResponseWriter, r *http.Request) {
+	req := BatchLookupRequest{}
+	if err := s.decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(req.Keys) > s.opts.maxBatchSize {
+		writeError(w, newBadRequestError("too many keys, max batch size is "+strconv.Itoa(s.opts.maxBatchSize)))
+		return
+	}
+	//未指定n时查找1个节点
+	if req.N == 0 {
+		req.N = 1
+	}
+	if err := s.checkN(req.N); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := BatchLookupResponse{Results: make([]LookupResult, 0, len(req.Keys))}
+	for _, key := range req.Keys {
+		result, err := s.lookupKey(r, key, req.N)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// n为1时与GetNode结果一致，否则查找n个不同的节点
+func (s *Server) lookupKey(r *http.Request, key string, n int) (LookupResult, error) {
+	result := LookupResult{Key: key}
+	if n == 1 {
+		nodeName, err := s.ch.GetNode(r.Context(), key)
+		if err != nil {
+			return result, err
+		}
+		result.Nodes = []string{nodeName}
+		return result, nil
+	}
+	nodeNames, err := s.ch.GetNodes(r.Context(), key, n)
+	if err != nil {
+		return result, err
+	}
+	if len(nodeNames) == 0 {
+		return result, csHash.ErrNoAvailableNode
+	}
+	result.Nodes = nodeNames
+	return result, nil
+}
+
+type Node struct {
+	Name     string           `json:"name"`
+	Replicas int64            `json:"replicas"`
+	State    csHash.NodeState `json:"state"`
+}
+
+func (s *Server) listNodes(w http.ResponseWriter, r *http.Request) {
+	nodes, err := s.ch.ListNodes(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]Node, 0, len(nodes))
+	for nodeName, node := range nodes {
+		resp = append(resp, Node{Name: nodeName, Replicas: node.Replicas, State: node.State})
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Name < resp[j].Name
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type AddNodeRequest struct {
+	Name   string `json:"name"`
+	Weight int64  `json:"weight"`
+}
+
+func (s *Server) addNode(w http.ResponseWriter, r *http.Request) {
+	req := AddNodeRequest{}
+	if err := s.decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Name == "" {
+		writeError(w, newBadRequestError("name is required"))
+		return
+	}
+	if err := s.ch.AddNode(r.Context(), req.Name, req.Weight); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeVersion(w, r, http.StatusCreated)
+}
+
+func (s *Server) removeNode(w http.ResponseWriter, r *http.Request) {
+	nodeName := r.PathValue("name")
+	nodes, err := s.ch.ListNodes(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, ok := nodes[nodeName]; !ok {
+		writeError(w, csHash.ErrNodeNotExists)
+		return
+	}
+
+	if r.URL.Query().Get("drain") == "true" {
+		err = s.ch.DrainNode(r.Context(), nodeName)
+	} else {
+		err = s.ch.RemoveNode(r.Context(), nodeName)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeVersion(w, r, http.StatusOK)
+}
+
+type UpdateNodeWeightRequest struct {
+	Weight int64 `json:"weight"`
+}
+
+func (s *Server) updateNodeWeight(w http.ResponseWriter, r *http.Request) {
+	req := UpdateNodeWeightRequest{}
+	if err := s.decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.ch.UpdateNodeWeight(r.Context(), r.PathValue("name"), req.Weight); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeVersion(w, r, http.StatusOK)
+}
+
+func (s *Server) dump(w http.ResponseWriter, r *http.Request) {
+	format := csHash.SnapshotFormatJSON
+	contentType := "application/json"
+	if r.URL.Query().Get("format") == string(csHash.SnapshotFormatYAML) {
+		format = csHash.SnapshotFormatYAML
+		contentType = "application/yaml"
+	}
+	snapshot, err := s.ch.Export(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_ = csHash.WriteRingSnapshot(w, snapshot, format)
+}
+
+type VersionResponse struct {
+	Version int64 `json:"version"`
+}
+
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	s.writeVersion(w, r, http.StatusOK)
+}
+
+func (s *Server) writeVersion(w http.ResponseWriter, r *http.Request, status int) {
+	version, err := s.ch.Version(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, status, VersionResponse{Version: version})
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.ch.Stats(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// 解析json请求体，请求体过大或格式错误时返回参数错误
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return newBadRequestError("request body is empty")
+		}
+		return newBadRequestError("invalid request body: " + err.Error())
+	}
+	return nil
+}
+
+func (s *Server) parseN(raw string) (int, error) {
+	if raw == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, newBadRequestError("n must be a positive integer")
+	}
+	return n, s.checkN(n)
+}
+
+// n需要处于[1, maxN]，避免按调用方传入的n分配过大的内存
+func (s *Server) checkN(n int) error {
+	if n <= 0 {
+		return newBadRequestError("n must be a positive integer")
+	}
+	if n > s.opts.maxN {
+		return newBadRequestError("n is too large, max n is " + strconv.Itoa(s.opts.maxN))
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, resp := toErrorResponse(err)
+	writeJSON(w, status, resp)
+}
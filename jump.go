@@ -0,0 +1,176 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 19:05:27
+ */
+
+package csHash
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+var _ Router = (*JumpHash)(nil)
+
+// Jump Consistent Hash（Lamping & Veach），适用于编号连续的存储分片
+// 查找不占用额外内存，数据在分片之间完全均匀；分片只能在末尾追加或删除，追加分片时只有约1/n的数据迁移到新分片
+//
+// 分片列表保存在哈希环的真实节点列表中，映射数量保存的是分片编号（从1开始），哈希环上不写入虚拟节点
+// 选项中只有WithLockExpireSeconds和WithHealthChecker生效
+type JumpHash struct {
+	hashRing  HashRing
+	encryptor HashEncryptor64
+	opts      ConsistentHashOptions
+}
+
+func NewJumpHash(hashRing HashRing, encryptor HashEncryptor64, opts ...ConsistentHashOption) *JumpHash {
+	j := JumpHash{
+		hashRing:  hashRing,
+		encryptor: encryptor,
+	}
+	for _, opt := range opts {
+		opt(&j.opts)
+	}
+	j.opts.repair()
+	return &j
+}
+
+// Lamping & Veach论文中的算法，返回 [0, numBuckets) 中的分片下标
+func jumpHash(key uint64, numBuckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// 在末尾追加分片，分片已存在时报错
+func (j *JumpHash) AddBucket(ctx context.Context, bucketName string) error {
+	if err := j.hashRing.Lock(ctx, j.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer j.hashRing.Unlock(ctx)
+
+	buckets, _, err := j.getBuckets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range buckets {
+		if name == bucketName {
+			return ErrNodeAlreadyExists
+		}
+	}
+	return j.hashRing.AddRealNode(ctx, bucketName, int64(len(buckets))+1)
+}
+
+// 删除末尾的分片，返回被删除的分片名，没有分片时返回ErrNodeNotExists
+func (j *JumpHash) RemoveLastBucket(ctx context.Context) (bucketName string, err error) {
+	if err := j.hashRing.Lock(ctx, j.opts.lockExpireSeconds); err != nil {
+		return "", err
+	}
+
+	defer j.hashRing.Unlock(ctx)
+
+	buckets, _, err := j.getBuckets(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(buckets) == 0 {
+		return "", ErrNodeNotExists
+	}
+	bucketName = buckets[len(buckets)-1]
+	return bucketName, j.hashRing.RemoveRealNode(ctx, bucketName)
+}
+
+// 按编号排列的分片列表
+func (j *JumpHash) Buckets(ctx context.Context) ([]string, error) {
+	buckets, _, err := j.getBuckets(ctx)
+	return buckets, err
+}
+
+// 读取分片列表以及分片信息，分片编号必须是从1开始的连续整数
+func (j *JumpHash) getBuckets(ctx context.Context) ([]string, map[string]RealNode, error) {
+	nodes, err := readRealNodeInfos(ctx, j.hashRing)
+	if err != nil {
+		return nil, nil, err
+	}
+	buckets := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		buckets = append(buckets, nodeName)
+	}
+	sort.Slice(buckets, func(a, b int) bool {
+		return nodes[buckets[a]].Replicas < nodes[buckets[b]].Replicas
+	})
+	for i, bucketName := range buckets {
+		if nodes[bucketName].Replicas != int64(i)+1 {
+			return nil, nil, fmt.Errorf("invalid bucket %s, expect index %d, got %d", bucketName, i+1, nodes[bucketName].Replicas)
+		}
+	}
+	return buckets, nodes, nil
+}
+
+// 查找数据所属的分片用于读取，分片不可用或不健康时顺延到下一个分片
+// 与ConsistentHash.GetNode一致，不跳过下线中的分片，写入新数据请使用GetNodeForWrite
+func (j *JumpHash) GetNode(ctx context.Context, dataKey string) (nodeName string, err error) {
+	return j.getNode(ctx, dataKey, false)
+}
+
+// 查找写入新数据的分片，与GetNode相比还会跳过下线中的分片
+func (j *JumpHash) GetNodeForWrite(ctx context.Context, dataKey string) (nodeName string, err error) {
+	return j.getNode(ctx, dataKey, true)
+}
+
+func (j *JumpHash) getNode(ctx context.Context, dataKey string, forWrite bool) (nodeName string, err error) {
+	nodeNames, err := j.getNodes(ctx, dataKey, 1, forWrite)
+	if err != nil {
+		return "", err
+	}
+	if len(nodeNames) == 0 {
+		return "", ErrNoAvailableNode
+	}
+	return nodeNames[0], nil
+}
+
+// 查找数据所属的n个不同的分片用于读取，从jump hash选中的分片开始按编号依次向后选取，跳过不可用、不健康的分片
+// 可用分片不足n个时返回全部可用分片，没有任何分片时返回ErrNodeNotExists
+// 与GetNode一样不跳过下线中的分片，写入新数据请使用GetNodesForWrite
+func (j *JumpHash) GetNodes(ctx context.Context, dataKey string, n int) (nodeNames []string, err error) {
+	return j.getNodes(ctx, dataKey, n, false)
+}
+
+// 查找写入新数据的n个不同的分片，与GetNodes相比还会跳过下线中的分片
+func (j *JumpHash) GetNodesForWrite(ctx context.Context, dataKey string, n int) (nodeNames []string, err error) {
+	return j.getNodes(ctx, dataKey, n, true)
+}
+
+func (j *JumpHash) getNodes(ctx context.Context, dataKey string, n int, forWrite bool) (nodeNames []string, err error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+	buckets, nodes, err := j.getBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(buckets) == 0 {
+		return nil, ErrNodeNotExists
+	}
+
+	start := jumpHash(j.encryptor.Encrypt64(dataKey), len(buckets))
+	nodeNames = make([]string, 0, min(n, len(buckets)))
+	for i := 0; i < len(buckets) && len(nodeNames) < n; i++ {
+		bucketName := buckets[(start+i)%len(buckets)]
+		if !nodes[bucketName].usable(forWrite) {
+			continue
+		}
+		if j.opts.healthChecker != nil && !j.opts.healthChecker.IsHealthy(ctx, bucketName) {
+			continue
+		}
+		nodeNames = append(nodeNames, bucketName)
+	}
+	return nodeNames, nil
+}
@@ -0,0 +1,144 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 16:40:12
+ */
+
+package csHash
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ketama中每个节点在权重均等时的摘要个数，每个摘要切出ketamaPointsPerDigest个点
+const (
+	ketamaDigestsPerNode  = 40
+	ketamaPointsPerDigest = 4
+)
+
+// 与libketama兼容的ConsistentHash，相同的节点和权重下与libketama、twemproxy等客户端路由一致
+//   - 数据key的score为MD5摘要前4个字节按小端序组成的uint32，twemproxy需要配置hash: md5
+//   - 节点的第k个摘要为MD5("节点名-k")，每个摘要按小端序切出4个点，节点名需要与其他客户端使用的字符串相同，如"10.0.0.1:11211"
+//   - 节点的点数为 floorf(权重 / 总权重 * 40 * 节点数) * 4，权重不限制上限，不使用WithReplicas
+//
+// 真实节点的映射数量保存的是权重，点数由所有节点的权重共同决定，增删节点、修改权重时所有节点的点数都会重新计算
+// 多个点落在同一个score上时由先加入的节点负责，libketama中这种情况的归属不确定
+func NewKetamaConsistentHash(
+	hashRing HashRing,
+	migrator Migrator,
+	opts ...ConsistentHashOption) *ConsistentHash {
+
+	return newConsistentHash(ConsistentHash{
+		hashRing: hashRing,
+		migrator: migrator,
+		ketama:   true,
+	}, opts)
+}
+
+// 数据key在ketama环上的score，取值为 [0, 2^32 - 1]
+func ketamaHash(origin string) int64 {
+	digest := md5.Sum([]byte(origin))
+	return int64(binary.LittleEndian.Uint32(digest[:4]))
+}
+
+// nodeName的第index个点（从1开始）：第(index-1)/4个摘要中的第(index-1)%4组4个字节
+func ketamaPoint(nodeName string, index int64) int64 {
+	digest := md5.Sum([]byte(fmt.Sprintf("%s-%d", nodeName, (index-1)/ketamaPointsPerDigest)))
+	offset := (index - 1) % ketamaPointsPerDigest * 4
+	return int64(binary.LittleEndian.Uint32(digest[offset : offset+4]))
+}
+
+// 每个节点的点数，按libketama的float精度计算，保证舍入结果一致，权重不大于0的节点不参与计算
+func ketamaPointCounts(weights map[string]int64) map[string]int64 {
+	var totalWeight, nodeCount int64
+	for _, weight := range weights {
+		if weight > 0 {
+			totalWeight += weight
+			nodeCount++
+		}
+	}
+	counts := make(map[string]int64, len(weights))
+	for nodeName, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		pct := float32(weight) / float32(totalWeight)
+		digests := math.Floor(float64(float32(float64(pct) * ketamaDigestsPerNode * float64(float32(nodeCount)))))
+		counts[nodeName] = int64(digests) * ketamaPointsPerDigest
+	}
+	return counts
+}
+
+// 把哈希环调整为weights（调整后所有真实节点的权重）对应的ketama环，nodes为调整前的权重
+// 只增删点数变化的部分，对比调整前后的哈希环，把归属变化的区间交给迁移回调，调用方需持有哈希环锁
+func (c *ConsistentHash) rebalanceKetama(ctx context.Context, nodes, weights map[string]int64) error {
+	oldCounts, newCounts := ketamaPointCounts(nodes), ketamaPointCounts(weights)
+	nodeNames := make([]string, 0, len(nodes)+len(weights))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	for nodeName := range weights {
+		if _, ok := nodes[nodeName]; !ok {
+			nodeNames = append(nodeNames, nodeName)
+		}
+	}
+	sort.Strings(nodeNames)
+
+	added, removed := make([]VirtualNodeEntry, 0), make([]VirtualNodeEntry, 0)
+	for _, nodeName := range nodeNames {
+		oldCount, newCount := oldCounts[nodeName], newCounts[nodeName]
+		if newCount > oldCount {
+			added = append(added, c.getVirtualNodeEntries(nodeName, oldCount+1, newCount)...)
+		} else {
+			removed = append(removed, c.getVirtualNodeEntries(nodeName, newCount+1, oldCount)...)
+		}
+	}
+
+	arcs, err := c.getMigrationArcs(ctx, added, removed)
+	if err != nil {
+		return err
+	}
+
+	// 与RemoveNode一致，先删除真实节点，查找时跳过所属真实节点不存在的虚拟节点
+	for _, nodeName := range nodeNames {
+		if _, ok := weights[nodeName]; !ok {
+			if err := c.hashRing.RemoveRealNode(ctx, nodeName); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.applyVirtualNodes(ctx, removed, false); err != nil {
+		return err
+	}
+	for _, nodeName := range nodeNames {
+		if weight, ok := weights[nodeName]; ok && weight != nodes[nodeName] {
+			if err := c.hashRing.AddRealNode(ctx, nodeName, weight); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.applyVirtualNodes(ctx, added, true); err != nil {
+		return err
+	}
+	c.refreshCacheAfterChange(ctx)
+	return c.migrate(ctx, arcs)
+}
+
+// 复制真实节点的权重并修改nodeName的权重，weight为0时删除该节点
+func withKetamaWeight(nodes map[string]int64, nodeName string, weight int64) map[string]int64 {
+	weights := make(map[string]int64, len(nodes)+1)
+	for name, w := range nodes {
+		weights[name] = w
+	}
+	if weight > 0 {
+		weights[nodeName] = weight
+	} else {
+		delete(weights, nodeName)
+	}
+	return weights
+}
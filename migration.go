@@ -10,3 +10,101 @@ import "context"
 
 // 迁移数据回调函数
 type Migrator func(ctx context.Context, dataKeys map[string]struct{}, from, to string) error
+
+// 迁移区间：(start, score]对应的数据从from节点迁移到to节点
+// start >= score时，表示跨越哈希环首尾的区间
+type migrationArc struct {
+	start int64
+	score int64
+	from  string
+	to    string
+}
+
+// 计算增删虚拟节点之后需要迁移的区间，必须在修改哈希环之前调用
+// 只读取一次修改前的整个哈希环，修改后的哈希环在本地推算，不会按虚拟节点逐个访问哈希环；未配置迁移回调时直接返回
+func (c *ConsistentHash) getMigrationArcs(ctx context.Context, added, removed []VirtualNodeEntry) ([]migrationArc, error) {
+	if c.migrator == nil || len(added)+len(removed) == 0 {
+		return nil, nil
+	}
+	before, _, err := c.getAllVirtualNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return diffMigrationArcs(before, applyVirtualNodeEntries(before, added, removed))
+}
+
+// 在hashScores的副本上删除removed、追加added，返回修改后的哈希环，不修改hashScores
+// 与HashRing的语义一致：新虚拟节点追加在score已有虚拟节点之后，已存在的虚拟节点忽略
+func applyVirtualNodeEntries(hashScores []*HashScore, added, removed []VirtualNodeEntry) []*HashScore {
+	byScore := make(map[int64]*HashScore, len(hashScores)+len(added))
+	for _, hashScore := range hashScores {
+		if hashScore == nil {
+			continue
+		}
+		virtualNodes := make([]VirtualNode, len(hashScore.VirtualNodes))
+		copy(virtualNodes, hashScore.VirtualNodes)
+		byScore[hashScore.Score] = &HashScore{Score: hashScore.Score, VirtualNodes: virtualNodes}
+	}
+
+	indexOf := func(hashScore *HashScore, virtualNodeID string) int {
+		for i, virtualNode := range hashScore.VirtualNodes {
+			if virtualNode.VirtualNodeID == virtualNodeID {
+				return i
+			}
+		}
+		return -1
+	}
+	for _, entry := range removed {
+		hashScore := byScore[entry.Score]
+		if hashScore == nil {
+			continue
+		}
+		if i := indexOf(hashScore, entry.VirtualNodeID); i >= 0 {
+			hashScore.VirtualNodes = append(hashScore.VirtualNodes[:i], hashScore.VirtualNodes[i+1:]...)
+		}
+	}
+	for _, entry := range added {
+		hashScore := byScore[entry.Score]
+		if hashScore == nil {
+			hashScore = &HashScore{Score: entry.Score}
+			byScore[entry.Score] = hashScore
+		}
+		if indexOf(hashScore, entry.VirtualNodeID) < 0 {
+			hashScore.VirtualNodes = append(hashScore.VirtualNodes, VirtualNode{VirtualNodeID: entry.VirtualNodeID})
+		}
+	}
+
+	result := make([]*HashScore, 0, len(byScore))
+	for _, hashScore := range byScore {
+		result = append(result, hashScore)
+	}
+	//newRingView会忽略没有虚拟节点的score并按score排序
+	return result
+}
+
+// 按区间依次调用迁移回调
+// 配置了数据key登记表时，只传递区间内的数据key，区间内没有数据则跳过
+func (c *ConsistentHash) migrate(ctx context.Context, arcs []migrationArc) error {
+	if c.migrator == nil {
+		return nil
+	}
+	for _, arc := range arcs {
+		if arc.from == arc.to {
+			continue
+		}
+		dataKeys := make(map[string]struct{})
+		if c.opts.dataKeyStore != nil {
+			var err error
+			if dataKeys, err = c.opts.dataKeyStore.ListKeys(ctx, arc.start, arc.score); err != nil {
+				return err
+			}
+			if len(dataKeys) == 0 {
+				continue
+			}
+		}
+		if err := c.migrator(ctx, dataKeys, arc.from, arc.to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
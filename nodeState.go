@@ -0,0 +1,193 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 16:32:40
+ */
+
+package csHash
+
+import "context"
+
+type NodeState string
+
+const (
+	// 正常提供服务
+	NodeStateActive NodeState = "active"
+	// 下线中，不再接收新数据，已有数据正在迁出
+	NodeStateDraining NodeState = "draining"
+	// 不可用，不再接收新数据
+	NodeStateDown NodeState = "down"
+)
+
+// 真实节点信息，与映射数量一起保存在真实节点列表中
+type RealNode struct {
+	Replicas int64     `json:"replicas" yaml:"replicas"`
+	State    NodeState `json:"state" yaml:"state"`
+}
+
+// 节点是否可以接收新数据，未设置状态视为正常
+func (n RealNode) available() bool {
+	return n.Replicas > 0 && (n.State == "" || n.State == NodeStateActive)
+}
+
+// 节点上的已有数据是否可以读取，下线中的节点在虚拟节点删除之前仍持有原有数据
+func (n RealNode) readable() bool {
+	return n.Replicas > 0 && n.State != NodeStateDown
+}
+
+// 写入时要求节点可以接收新数据，读取时只要求节点上的已有数据可读
+func (n RealNode) usable(forWrite bool) bool {
+	if forWrite {
+		return n.available()
+	}
+	return n.readable()
+}
+
+// 修改节点状态，节点不存在时报错，哈希环未实现StatefulHashRing时返回ErrRingNotSupported
+func (c *ConsistentHash) SetNodeState(ctx context.Context, nodeName string, state NodeState) error {
+	if _, ok := c.hashRing.(StatefulHashRing); !ok {
+		return ErrRingNotSupported
+	}
+	if err := c.hashRing.Lock(ctx, c.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer c.hashRing.Unlock(ctx)
+
+	if err := c.saveSnapshot(ctx); err != nil {
+		return err
+	}
+	defer c.saveSnapshotAfterChange(ctx)
+
+	nodes, err := readRealNodeInfos(ctx, c.hashRing)
+	if err != nil {
+		return err
+	}
+	node, ok := nodes[nodeName]
+	if !ok {
+		return ErrNodeNotExists
+	}
+	// 下线过程中逐个删除虚拟节点都属于这次操作，直到下一次逻辑操作开始；
+	// 状态不变时（例如中途失败后再次调用DrainNode）沿用之前的操作
+	if node.State != state {
+		if err := c.beginOperation(ctx); err != nil {
+			return err
+		}
+	}
+	if err := setRealNodeState(ctx, c.hashRing, nodeName, state); err != nil {
+		return err
+	}
+	c.refreshCacheAfterChange(ctx)
+	return nil
+}
+
+// 平滑下线节点：先将节点标记为下线中，GetNodeForWrite不再把新数据分配给它，GetNode在数据迁出之前仍然返回它；
+// 之后删除虚拟节点，把对应区间的数据交给迁移回调，删除最后一个虚拟节点时同时删除真实节点
+// 哈希环实现了BatchHashRing时一次性删除全部虚拟节点，只产生一个版本；否则逐个删除，每个虚拟节点单独加锁
+// 中途失败后再次调用会从剩余的虚拟节点继续；删除真实节点之后失败时与RemoveNode一样会残留虚拟节点，可以通过Verify发现
+// 迁移目标是删除之后读取时的归属，跳过不可用的节点；下线期间写入的数据已经分配给了其他节点，
+// 但仍会出现在迁移回调的dataKeys中，迁移回调需要容忍源节点上不存在的数据
+// ketama模式下删除节点会改变其他节点的点数，标记为下线中之后一次性删除
+// 需要哈希环实现StatefulHashRing，否则返回ErrRingNotSupported
+func (c *ConsistentHash) DrainNode(ctx context.Context, nodeName string) error {
+	if err := c.SetNodeState(ctx, nodeName, NodeStateDraining); err != nil {
+		return err
+	}
+	if c.ketama {
+		return c.RemoveNode(ctx, nodeName)
+	}
+
+	for {
+		done, err := c.drainVirtualNode(ctx, nodeName)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// 删除节点编号最大的虚拟节点（支持批量修改时删除全部剩余虚拟节点）并迁移其区间的数据，删除最后一个虚拟节点时同时删除真实节点并返回true
+// 与RemoveNode一致，先修改真实节点，再删除虚拟节点递增版本号，按版本号缓存真实节点信息的进程不会读到旧的映射数量和状态
+func (c *ConsistentHash) drainVirtualNode(ctx context.Context, nodeName string) (done bool, err error) {
+	if err := c.hashRing.Lock(ctx, c.opts.lockExpireSeconds); err != nil {
+		return false, err
+	}
+
+	defer c.hashRing.Unlock(ctx)
+
+	replicas, err := c.hashRing.GetRealNode(ctx, nodeName)
+	if err != nil {
+		return false, err
+	}
+	if replicas <= 0 {
+		// 没有剩余的虚拟节点，删除真实节点后单独递增版本号
+		defer c.saveSnapshotAfterChange(ctx)
+		if err := c.hashRing.RemoveRealNode(ctx, nodeName); err != nil {
+			return false, err
+		}
+		if err := c.incrVersion(ctx); err != nil {
+			return false, err
+		}
+		c.refreshCacheAfterChange(ctx)
+		return true, nil
+	}
+
+	start := replicas
+	if _, ok := c.hashRing.(BatchHashRing); ok {
+		start = 1
+	}
+	virtualNodes := c.getVirtualNodeEntries(nodeName, start, replicas)
+	arcs, err := c.getDrainMigrationArcs(ctx, virtualNodes)
+	if err != nil {
+		return false, err
+	}
+	if start == 1 {
+		// 逐个删除虚拟节点的中间版本不保存快照，删除真实节点后为最终版本保存快照
+		defer c.saveSnapshotAfterChange(ctx)
+		err = c.hashRing.RemoveRealNode(ctx, nodeName)
+	} else {
+		// 同步减少映射数量，保证中途失败时真实节点列表与哈希环一致
+		err = c.hashRing.AddRealNode(ctx, nodeName, start-1)
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := c.applyVirtualNodes(ctx, virtualNodes, false); err != nil {
+		return false, err
+	}
+	c.refreshCacheAfterChange(ctx)
+
+	if err := c.migrate(ctx, arcs); err != nil {
+		return false, err
+	}
+	return start == 1, nil
+}
+
+// 版本号加一，用于没有修改虚拟节点、哈希环不会自动递增版本号的变更
+func (c *ConsistentHash) incrVersion(ctx context.Context) error {
+	version, err := c.hashRing.GetVersion(ctx)
+	if err != nil {
+		return err
+	}
+	return c.hashRing.SetVersion(ctx, version+1)
+}
+
+// 计算下线时删除虚拟节点需要迁移的区间，必须在修改哈希环之前调用
+// 迁移目标按节点状态查找：跳过不可用的节点，但不跳过下线中的节点，与删除之后GetNode的归属一致；
+// 区间的后继仍是正在下线的节点时不迁移，等它的最后一个虚拟节点删除时再迁出
+func (c *ConsistentHash) getDrainMigrationArcs(ctx context.Context, removed []VirtualNodeEntry) ([]migrationArc, error) {
+	if c.migrator == nil || len(removed) == 0 {
+		return nil, nil
+	}
+	nodes, err := readRealNodeInfos(ctx, c.hashRing)
+	if err != nil {
+		return nil, err
+	}
+	before, _, err := c.getAllVirtualNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return diffMigrationArcsWithNodes(before, applyVirtualNodeEntries(before, nil, removed), nodes)
+}
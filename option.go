@@ -6,6 +6,8 @@
 
 package csHash
 
+import "time"
+
 type ConsistentHashOption func(*ConsistentHashOptions)
 
 type ConsistentHashOptions struct {
@@ -15,6 +17,24 @@ type ConsistentHashOptions struct {
 	replicas int64
 	//日志级别
 	loggerLevel LoggerLevel
+	//数据key登记表
+	dataKeyStore DataKeyStore
+	//GetNode时是否登记数据key
+	recordOnGetNode bool
+	//是否开启哈希环本地缓存
+	localCache bool
+	//本地缓存轮询版本号的间隔
+	cacheRefreshInterval time.Duration
+	//日志
+	logger Logger
+	//有界负载模式的负载系数，为0时不开启
+	loadFactor float64
+	//节点健康检查
+	healthChecker HealthChecker
+	//是否在每次修改前后保存哈希环快照
+	snapshot bool
+	//最多保留的快照数量，为0时不限制
+	snapshotLimit int64
 }
 
 // lockExpireSeconds 锁的过期时间，单位秒, 默认15秒
@@ -31,6 +51,63 @@ func WithReplicas(replicas int64) ConsistentHashOption {
 	}
 }
 
+// dataKeyStore 数据key登记表，节点变更时只将受影响区间内的数据key交给迁移回调
+func WithDataKeyStore(store DataKeyStore) ConsistentHashOption {
+	return func(opts *ConsistentHashOptions) {
+		opts.dataKeyStore = store
+	}
+}
+
+// GetNode时自动将数据key登记到数据key登记表，需同时配置WithDataKeyStore
+func WithRecordOnGetNode() ConsistentHashOption {
+	return func(opts *ConsistentHashOptions) {
+		opts.recordOnGetNode = true
+	}
+}
+
+// 开启哈希环本地缓存，GetNode直接在本地副本上查找
+// refreshInterval > 0 时按该间隔轮询哈希环版本号，版本变化后重新加载；否则只在调用RefreshCache时刷新
+func WithLocalCache(refreshInterval time.Duration) ConsistentHashOption {
+	return func(opts *ConsistentHashOptions) {
+		opts.localCache = true
+		opts.cacheRefreshInterval = refreshInterval
+	}
+}
+
+// 开启有界负载模式，每个真实节点的负载不超过按权重分配的平均负载的loadFactor倍，默认1.25
+// 负载通过ConsistentHash.Inc/Done上报
+func WithBoundedLoad(loadFactor float64) ConsistentHashOption {
+	return func(opts *ConsistentHashOptions) {
+		opts.loadFactor = loadFactor
+		if loadFactor <= 1 {
+			opts.loadFactor = 1.25
+		}
+	}
+}
+
+// healthChecker 查找时顺时针跳过不健康的节点，不修改哈希环
+func WithHealthChecker(healthChecker HealthChecker) ConsistentHashOption {
+	return func(opts *ConsistentHashOptions) {
+		opts.healthChecker = healthChecker
+	}
+}
+
+// 开启哈希环快照，哈希环需要实现SnapshotHashRing
+// 每次修改哈希环前后按版本号保存快照，超过limit个时删除最旧的快照，limit <= 0 时不限制
+func WithSnapshot(limit int64) ConsistentHashOption {
+	return func(opts *ConsistentHashOptions) {
+		opts.snapshot = true
+		opts.snapshotLimit = max(limit, 0)
+	}
+}
+
+// logger 记录后台任务中出现的错误
+func WithLogger(logger Logger) ConsistentHashOption {
+	return func(opts *ConsistentHashOptions) {
+		opts.logger = logger
+	}
+}
+
 func (opts *ConsistentHashOptions) repair() {
 	//必须有超时时限
 	if opts.lockExpireSeconds <= 0 {
@@ -44,3 +121,61 @@ func (opts *ConsistentHashOptions) repair() {
 		opts.replicas = 10
 	}
 }
+
+type HealthCheckerOption func(*HealthCheckerOptions)
+
+type HealthCheckerOptions struct {
+	//探测间隔
+	interval time.Duration
+	//单次探测超时时间
+	timeout time.Duration
+	//连续失败多少次视为不健康
+	failureThreshold int64
+}
+
+// interval 探测间隔，默认5秒
+func WithProbeInterval(interval time.Duration) HealthCheckerOption {
+	return func(opts *HealthCheckerOptions) {
+		opts.interval = interval
+	}
+}
+
+// timeout 单次探测超时时间，默认1秒
+func WithProbeTimeout(timeout time.Duration) HealthCheckerOption {
+	return func(opts *HealthCheckerOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// failureThreshold 连续失败多少次视为不健康，默认2次
+func WithFailureThreshold(failureThreshold int64) HealthCheckerOption {
+	return func(opts *HealthCheckerOptions) {
+		opts.failureThreshold = failureThreshold
+	}
+}
+
+func (opts *HealthCheckerOptions) repair() {
+	if opts.interval <= 0 {
+		opts.interval = 5 * time.Second
+	}
+	if opts.timeout <= 0 {
+		opts.timeout = time.Second
+	}
+	if opts.failureThreshold <= 0 {
+		opts.failureThreshold = 2
+	}
+}
+
+type EncryptorOption func(*EncryptorOptions)
+
+type EncryptorOptions struct {
+	//SipHash的key，默认全0
+	sipHashKey [16]byte
+}
+
+// sipHashKey 按名称创建SipHash时使用的key，只对HashSipHash生效
+func WithSipHashKey(key [16]byte) EncryptorOption {
+	return func(opts *EncryptorOptions) {
+		opts.sipHashKey = key
+	}
+}
@@ -0,0 +1,408 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 11:40:09
+ */
+
+package redisHashRing
+
+// 哈希环脚本公共函数，拼接在各个脚本之前使用
+const luaRingHelpers = `
+-- zset的score是double，超过2^53的score会丢失精度，不同的score可能对应同一个double
+-- 脚本中score一律按十进制字符串处理，数据的精确score以成员中编码的score字段为准
+local function entry_score(entry)
+  return string.match(entry, '"score":(%-?%d+)')
+end
+
+-- 比较两个十进制整数字符串，a小于、等于、大于b时分别返回-1、0、1
+local function compare_score(a, b)
+  local negativeA, negativeB = string.sub(a, 1, 1) == '-', string.sub(b, 1, 1) == '-'
+  if negativeA ~= negativeB then
+    return negativeA and -1 or 1
+  end
+  if a == b then
+    return 0
+  end
+  local result
+  if #a ~= #b then
+    result = #a < #b and -1 or 1
+  else
+    result = a < b and -1 or 1
+  end
+  return negativeA and -result or result
+end
+
+-- 返回entries中精确score满足accept的数据中score最小的数据，accept为nil时不过滤
+local function pick_entry(entries, accept)
+  local picked, pickedScore
+  for _, entry in ipairs(entries) do
+    local score = entry_score(entry)
+    if accept == nil or accept(score) then
+      if picked == nil or compare_score(score, pickedScore) < 0 then
+        picked, pickedScore = entry, score
+      end
+    end
+  end
+  return picked
+end
+
+-- 返回与double score相等的所有数据
+local function bucket_entries(ring, score)
+  return redis.call('ZRANGE', ring, score, score, 'BYSCORE')
+end
+
+-- 编码数据，score以字符串写入后还原为JSON数字，避免按double编码丢失精度
+local function encode(value)
+  return (string.gsub(cjson.encode(value), '"score":"(%-?%d+)"', '"score":%1'))
+end
+
+-- 解码score上的数据，虚拟节点的字段名统一为virtual_node_id、version，兼容VirtualNodeID、Version的旧数据
+local function decode_hash_score(entry)
+  local hashScore = cjson.decode(entry)
+  for i, virtualNode in ipairs(hashScore.virtual_nodes) do
+    hashScore.virtual_nodes[i] = {
+      virtual_node_id = virtualNode.virtual_node_id or virtualNode.VirtualNodeID,
+      version = virtualNode.version or virtualNode.Version,
+    }
+  end
+  return hashScore
+end
+
+-- 读取score上的数据，返回原始成员和解码后的hashScore，不存在时均返回nil
+local function get_hash_score(ring, score)
+  local entry = pick_entry(bucket_entries(ring, score), function(s) return s == score end)
+  if entry == nil then
+    return nil, nil
+  end
+  return entry, decode_hash_score(entry)
+end
+
+-- 写回score上的数据，hashScore中已经没有虚拟节点时直接删除score
+local function set_hash_score(ring, score, entry, hashScore)
+  if entry then
+    redis.call('ZREM', ring, entry)
+  end
+  if #hashScore.virtual_nodes > 0 then
+    hashScore.score = score
+    redis.call('ZADD', ring, score, encode(hashScore))
+  end
+end
+
+-- 返回虚拟节点在hashScore中的下标，不存在返回0
+local function find_virtual_node(hashScore, nodeID)
+  for i, virtualNode in ipairs(hashScore.virtual_nodes) do
+    if virtualNode.virtual_node_id == nodeID then
+      return i
+    end
+  end
+  return 0
+end
+
+-- 在score上追加虚拟节点，已存在返回false
+local function add_virtual_node(ring, score, nodeID, version)
+  local entry, hashScore = get_hash_score(ring, score)
+  if hashScore == nil then
+    hashScore = {score = score, virtual_nodes = {}}
+  elseif find_virtual_node(hashScore, nodeID) > 0 then
+    return false
+  end
+  table.insert(hashScore.virtual_nodes, {virtual_node_id = nodeID, version = version})
+  set_hash_score(ring, score, entry, hashScore)
+  return true
+end
+
+-- 删除score上的虚拟节点，不存在返回false
+local function remove_virtual_node(ring, score, nodeID)
+  local entry, hashScore = get_hash_score(ring, score)
+  if hashScore == nil then
+    return false
+  end
+  local index = find_virtual_node(hashScore, nodeID)
+  if index == 0 then
+    return false
+  end
+  table.remove(hashScore.virtual_nodes, index)
+  set_hash_score(ring, score, entry, hashScore)
+  return true
+end
+
+-- 顺时针查找第一个精确score大于等于score的数据，到达环尾返回nil
+local function ceiling_entry(ring, score)
+  local entry = pick_entry(bucket_entries(ring, score), function(s) return compare_score(s, score) >= 0 end)
+  if entry then
+    return entry
+  end
+  local entries = redis.call('ZRANGE', ring, '(' .. score, '+inf', 'BYSCORE', 'LIMIT', 0, 1, 'WITHSCORES')
+  if #entries == 0 then
+    return nil
+  end
+  return pick_entry(bucket_entries(ring, entries[2]))
+end
+
+-- 环首的数据，环为空返回nil
+local function first_entry(ring)
+  local entries = redis.call('ZRANGE', ring, 0, 0, 'WITHSCORES')
+  if #entries == 0 then
+    return nil
+  end
+  return pick_entry(bucket_entries(ring, entries[2]))
+end
+
+-- 顺时针查找第一个score大于等于目标score的数据，到达环尾则从环首继续查找，环为空返回nil
+local function find_hash_score(ring, score)
+  local entry = ceiling_entry(ring, score) or first_entry(ring)
+  if entry == nil then
+    return nil
+  end
+  return decode_hash_score(entry)
+end
+
+-- 记入当前逻辑操作并发布修改通知，只保留最近4096条记录，与csHash.MaxOperationChanges一致
+local function record_change(channel, changesKey, change)
+  local payload = encode(change)
+  redis.call('RPUSH', changesKey, payload)
+  redis.call('LTRIM', changesKey, -4096, -1)
+  redis.call('PUBLISH', channel, payload)
+end
+
+-- 记录并发布虚拟节点修改
+local function publish_change(channel, changesKey, version, changeType, virtualNodes)
+  record_change(channel, changesKey, {version = version, type = changeType, virtual_nodes = virtualNodes})
+end
+
+-- 读取真实节点信息，兼容只保存了映射数量的旧数据，不存在返回nil
+local function get_real_node(nodes, nodeName)
+  local raw = redis.call('HGET', nodes, nodeName)
+  if not raw then
+    return nil
+  end
+  local ok, node = pcall(cjson.decode, raw)
+  if not ok then
+    return nil
+  end
+  if type(node) == 'number' then
+    return {replicas = node, state = 'active'}
+  end
+  return node
+end
+
+-- 当前版本号
+local function get_version(versionKey)
+  return tonumber(redis.call('GET', versionKey) or 0)
+end
+`
+
+// 添加虚拟节点，读取score上的数据、追加虚拟节点、版本号加一、发布修改通知在一个脚本中原子完成
+// KEYS[1]: 哈希环zset KEYS[2]: 版本号 KEYS[3]: 当前逻辑操作的修改记录 ARGV[1]: 修改通知channel ARGV[2]: score ARGV[3]: 虚拟节点id
+// 返回虚拟节点的版本号，虚拟节点已存在时返回其原有版本号
+const luaAddVirtualNode = luaRingHelpers + `
+local _, hashScore = get_hash_score(KEYS[1], ARGV[2])
+if hashScore ~= nil then
+  local index = find_virtual_node(hashScore, ARGV[3])
+  if index > 0 then
+    return hashScore.virtual_nodes[index].version
+  end
+end
+
+local version = redis.call('INCR', KEYS[2])
+add_virtual_node(KEYS[1], ARGV[2], ARGV[3], version)
+publish_change(ARGV[1], KEYS[3], version, 'add', {{score = ARGV[2], virtual_node_id = ARGV[3]}})
+return version
+`
+
+// 删除虚拟节点，读取score上的数据、删除虚拟节点、版本号加一、发布修改通知在一个脚本中原子完成
+// KEYS[1]: 哈希环zset KEYS[2]: 版本号 KEYS[3]: 当前逻辑操作的修改记录 ARGV[1]: 修改通知channel ARGV[2]: score ARGV[3]: 虚拟节点id
+// 返回删除后的版本号，虚拟节点不存在时返回0
+const luaRemoveVirtualNode = luaRingHelpers + `
+if not remove_virtual_node(KEYS[1], ARGV[2], ARGV[3]) then
+  return 0
+end
+local version = redis.call('INCR', KEYS[2])
+publish_change(ARGV[1], KEYS[3], version, 'remove', {{score = ARGV[2], virtual_node_id = ARGV[3]}})
+return version
+`
+
+// 批量添加虚拟节点，整批虚拟节点共用一个版本号、只发布一次修改通知，已存在的虚拟节点忽略
+// KEYS[1]: 哈希环zset KEYS[2]: 版本号 KEYS[3]: 当前逻辑操作的修改记录 ARGV[1]: 修改通知channel ARGV[2...]: score1, 虚拟节点id1, score2, 虚拟节点id2...
+// 返回添加后的版本号，没有任何修改时返回当前版本号
+const luaAddVirtualNodes = luaRingHelpers + `
+local version = get_version(KEYS[2]) + 1
+local changed = {}
+for i = 2, #ARGV, 2 do
+  if add_virtual_node(KEYS[1], ARGV[i], ARGV[i + 1], version) then
+    table.insert(changed, {score = ARGV[i], virtual_node_id = ARGV[i + 1]})
+  end
+end
+if #changed == 0 then
+  return version - 1
+end
+redis.call('INCR', KEYS[2])
+publish_change(ARGV[1], KEYS[3], version, 'add', changed)
+return version
+`
+
+// 批量删除虚拟节点，整批只增加一次版本号、只发布一次修改通知，不存在的虚拟节点忽略
+// KEYS[1]: 哈希环zset KEYS[2]: 版本号 KEYS[3]: 当前逻辑操作的修改记录 ARGV[1]: 修改通知channel ARGV[2...]: score1, 虚拟节点id1, score2, 虚拟节点id2...
+// 返回删除后的版本号，没有任何修改时返回当前版本号
+const luaRemoveVirtualNodes = luaRingHelpers + `
+local changed = {}
+for i = 2, #ARGV, 2 do
+  if remove_virtual_node(KEYS[1], ARGV[i], ARGV[i + 1]) then
+    table.insert(changed, {score = ARGV[i], virtual_node_id = ARGV[i + 1]})
+  end
+end
+if #changed == 0 then
+  return get_version(KEYS[2])
+end
+local version = redis.call('INCR', KEYS[2])
+publish_change(ARGV[1], KEYS[3], version, 'remove', changed)
+return version
+`
+
+// 根据数据score顺时针查找虚拟节点，查找与环尾回绕在一个脚本中完成，读到的总是某个完整版本的哈希环
+// KEYS[1]: 哈希环zset ARGV[1]: 数据score
+// 返回虚拟节点id，哈希环为空时返回nil
+const luaFindDataToVirtualNode = luaRingHelpers + `
+local hashScore = find_hash_score(KEYS[1], ARGV[1])
+if hashScore == nil then
+  return false
+end
+return hashScore.virtual_nodes[1].virtual_node_id
+`
+
+// 读取score大于等于startScore的数据，不回到环首
+// KEYS[1]: 哈希环zset ARGV[1]: startScore ARGV[2]: limit
+// 返回的数据只保证包含按精确score升序的前limit个（不足时为全部），由调用方排序并截断
+// 按double分页时最后一个double上的数据可能被LIMIT截断，因此每页都补齐最后一个double上的全部数据
+const luaRangeVirtualNodes = luaRingHelpers + `
+local limit = tonumber(ARGV[2])
+local result = {}
+for _, entry in ipairs(bucket_entries(KEYS[1], ARGV[1])) do
+  if compare_score(entry_score(entry), ARGV[1]) >= 0 then
+    table.insert(result, entry)
+  end
+end
+local cursor = '(' .. ARGV[1]
+while #result < limit do
+  local entries = redis.call('ZRANGE', KEYS[1], cursor, '+inf', 'BYSCORE', 'LIMIT', 0, limit - #result, 'WITHSCORES')
+  if #entries == 0 then
+    break
+  end
+  local last = entries[#entries]
+  for i = 1, #entries, 2 do
+    if entries[i + 1] ~= last then
+      table.insert(result, entries[i])
+    end
+  end
+  for _, entry in ipairs(bucket_entries(KEYS[1], last)) do
+    table.insert(result, entry)
+  end
+  cursor = '(' .. last
+end
+return result
+`
+
+// 读取整个哈希环以及版本号
+// KEYS[1]: 哈希环zset KEYS[2]: 版本号
+// 返回数组，首元素为版本号，之后为按double score升序排列的数据，由调用方按精确score排序
+const luaGetAllVirtualNodes = luaRingHelpers + `
+local entries = redis.call('ZRANGE', KEYS[1], 0, -1)
+table.insert(entries, 1, get_version(KEYS[2]))
+return entries
+`
+
+// 设置真实节点的映射数量，节点已存在时保留节点状态
+// KEYS[1]: 真实节点hash ARGV[1]: 真实节点名 ARGV[2]: 映射数量
+const luaAddRealNode = luaRingHelpers + `
+local state = 'active'
+local node = get_real_node(KEYS[1], ARGV[1])
+if node ~= nil and node.state then
+  state = node.state
+end
+redis.call('HSET', KEYS[1], ARGV[1], cjson.encode({replicas = tonumber(ARGV[2]), state = state}))
+return 1
+`
+
+// 修改真实节点状态，版本号加一，记录并发布修改通知
+// KEYS[1]: 真实节点hash KEYS[2]: 版本号 KEYS[3]: 当前逻辑操作的修改记录 ARGV[1]: 修改通知channel ARGV[2]: 真实节点名 ARGV[3]: 节点状态
+// 返回修改后的版本号，节点不存在时返回0
+const luaSetRealNodeState = luaRingHelpers + `
+local node = get_real_node(KEYS[1], ARGV[2])
+if node == nil then
+  return 0
+end
+node.state = ARGV[3]
+redis.call('HSET', KEYS[1], ARGV[2], cjson.encode(node))
+local version = redis.call('INCR', KEYS[2])
+record_change(ARGV[1], KEYS[3], {version = version, type = 'state', node_name = ARGV[2], state = ARGV[3]})
+return version
+`
+
+// 用快照整体替换哈希环，版本号加一，记录并发布修改通知
+// KEYS[1]: 哈希环zset KEYS[2]: 版本号 KEYS[3]: 当前逻辑操作的修改记录 KEYS[4]: 真实节点hash
+// ARGV[1]: 修改通知channel ARGV[2]: score数量n ARGV[3...]: n组score, 编码后的数据，之后依次为真实节点名, 真实节点信息
+// 返回替换后的版本号
+const luaResetRing = luaRingHelpers + `
+redis.call('DEL', KEYS[1], KEYS[4])
+local index = 3
+for _ = 1, tonumber(ARGV[2]) do
+  redis.call('ZADD', KEYS[1], ARGV[index], ARGV[index + 1])
+  index = index + 2
+end
+for i = index, #ARGV, 2 do
+  redis.call('HSET', KEYS[4], ARGV[i], ARGV[i + 1])
+end
+local version = redis.call('INCR', KEYS[2])
+record_change(ARGV[1], KEYS[3], {version = version, type = 'reset'})
+return version
+`
+
+// 开始一次新的逻辑操作，以当前版本号作为起始版本号并清空修改记录
+// KEYS[1]: 版本号 KEYS[2]: 逻辑操作起始版本号 KEYS[3]: 修改记录
+const luaBeginOperation = luaRingHelpers + `
+redis.call('SET', KEYS[2], get_version(KEYS[1]))
+redis.call('DEL', KEYS[3])
+return 1
+`
+
+// 原子读取最近一次逻辑操作
+// KEYS[1]: 逻辑操作起始版本号 KEYS[2]: 修改记录
+// 返回起始版本号，之后依次为按版本号升序排列的修改记录
+const luaGetLastOperation = `
+local result = {redis.call('GET', KEYS[1]) or '0'}
+for _, payload in ipairs(redis.call('LRANGE', KEYS[2], 0, -1)) do
+  table.insert(result, payload)
+end
+return result
+`
+
+// 登记数据key，zset保存近似score用于范围查询，hash保存精确score
+// KEYS[1]: 数据key zset KEYS[2]: 精确score hash ARGV[1]: score ARGV[2]: 数据key
+const luaRegisterDataKey = `
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+redis.call('HSET', KEYS[2], ARGV[2], ARGV[1])
+return 1
+`
+
+// 注销数据key
+// KEYS[1]: 数据key zset KEYS[2]: 精确score hash ARGV[1]: 数据key
+const luaUnregisterDataKey = `
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+return 1
+`
+
+// 按score范围查询数据key，范围包含两个端点
+// KEYS[1]: 数据key zset KEYS[2]: 精确score hash ARGV[...]: 若干组min, max
+// 依次返回数据key, 精确score，没有精确score的旧数据返回zset中的score
+const luaListDataKeys = `
+local result = {}
+for i = 1, #ARGV, 2 do
+  local entries = redis.call('ZRANGE', KEYS[1], ARGV[i], ARGV[i + 1], 'BYSCORE', 'WITHSCORES')
+  for j = 1, #entries, 2 do
+    table.insert(result, entries[j])
+    table.insert(result, redis.call('HGET', KEYS[2], entries[j]) or entries[j + 1])
+  end
+end
+return result
+`
@@ -39,10 +39,8 @@ func NewClient(network, address, password string, opts ...ClientOption) *Client
 	}
 
 	repairClient(c.opts)
-	pool := c.getRedisPool()
-	return &Client{
-		pool: pool,
-	}
+	c.pool = c.getRedisPool()
+	return &c
 }
 
 func (c *Client) getRedisPool() *redis.Pool {
@@ -69,6 +67,30 @@ func (c *Client) GetConn(ctx context.Context) (redis.Conn, error) {
 	return c.pool.GetContext(ctx)
 }
 
+// 使用独立连接订阅channel，连接不归还连接池，使用方负责关闭
+func (c *Client) Subscribe(ctx context.Context, channel string) (*redis.PubSubConn, error) {
+	conn, err := c.getRedisConn()
+	if err != nil {
+		return nil, err
+	}
+	psc := &redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(channel); err != nil {
+		psc.Close()
+		return nil, err
+	}
+	//等待订阅确认，保证返回之后发布的消息都能收到
+	switch reply := psc.ReceiveContext(ctx).(type) {
+	case redis.Subscription:
+		return psc, nil
+	case error:
+		psc.Close()
+		return nil, reply
+	default:
+		psc.Close()
+		return nil, fmt.Errorf("unexpected subscribe reply: %v", reply)
+	}
+}
+
 func (c *Client) getRedisConn() (redis.Conn, error) {
 	if c.opts.address == "" {
 		panic("Cannot get redis address from config")
@@ -89,7 +111,6 @@ func (c *Client) getRedisConn() (redis.Conn, error) {
 // ZAdd 执行Redis ZAdd 命令.
 func (c *Client) ZAdd(ctx context.Context, table string, score int64, value string) error {
 	conn, err := c.pool.GetContext(ctx)
-	c.pool.Get()
 	if err != nil {
 		return err
 	}
@@ -239,6 +260,36 @@ func (c *Client) HGetAll(ctx context.Context, table string) (map[string]string,
 	return redis.StringMap(conn.Do("HGETALL", table))
 }
 
+// hash表：key不存在时才插入，返回是否插入成功
+func (c *Client) HSetNX(ctx context.Context, table, key, val string) (bool, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	return redis.Bool(conn.Do("HSETNX", table, key, val))
+}
+
+// 获取哈希表table中key对应的值，不存在时返回redis.ErrNil
+func (c *Client) HGet(ctx context.Context, table, key string) (string, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return redis.String(conn.Do("HGET", table, key))
+}
+
+// 获取哈希表table的所有key
+func (c *Client) HKeys(ctx context.Context, table string) ([]string, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return redis.Strings(conn.Do("HKEYS", table))
+}
+
 // 删除哈希表table中的key-value
 func (c *Client) HDel(ctx context.Context, table, key string) error {
 	conn, err := c.pool.GetContext(ctx)
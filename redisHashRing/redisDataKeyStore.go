@@ -0,0 +1,106 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 10:26:51
+ */
+
+package redisHashRing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/gomodule/redigo/redis"
+)
+
+// 基于redis有序集合的数据key登记表，成员为数据key，分数为数据key的score
+// zset按double保存score，超过2^53的score会丢失精度，数据key的精确score另外保存在hash中，查询时按精确score过滤
+type RedisDataKeyStore struct {
+	key         string
+	redisClient *Client
+}
+
+func NewRedisDataKeyStore(key string, redisClient *Client) *RedisDataKeyStore {
+	return &RedisDataKeyStore{
+		key:         key,
+		redisClient: redisClient,
+	}
+}
+
+// zset name
+func (r *RedisDataKeyStore) getDataKeysKey() string {
+	return fmt.Sprintf("redis:consistent_hash:ring:data_keys:%s", r.key)
+}
+
+// 数据key的精确score，hash name
+func (r *RedisDataKeyStore) getDataKeyScoresKey() string {
+	return fmt.Sprintf("redis:consistent_hash:ring:data_key_scores:%s", r.key)
+}
+
+func (r *RedisDataKeyStore) RegisterKey(ctx context.Context, dataKey string, score int64) error {
+	keysAndArgs := []interface{}{r.getDataKeysKey(), r.getDataKeyScoresKey(), strconv.FormatInt(score, 10), dataKey}
+	if _, err := r.redisClient.Eval(ctx, luaRegisterDataKey, 2, keysAndArgs); err != nil {
+		return fmt.Errorf("redis data key store register failed, err: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisDataKeyStore) UnregisterKey(ctx context.Context, dataKey string) error {
+	keysAndArgs := []interface{}{r.getDataKeysKey(), r.getDataKeyScoresKey(), dataKey}
+	if _, err := r.redisClient.Eval(ctx, luaUnregisterDataKey, 2, keysAndArgs); err != nil {
+		return fmt.Errorf("redis data key store unregister failed, err: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisDataKeyStore) ListKeys(ctx context.Context, startScore, endScore int64) (map[string]struct{}, error) {
+	//按double查询的区间包含两个端点，与端点舍入到同一个double的数据key也会被查出，之后按精确score过滤，
+	//因此区间左端点不需要加一，startScore为MaxInt64时也不会溢出
+	ranges := []interface{}{startScore, endScore}
+	//跨越哈希环首尾的区间拆成两段
+	if startScore >= endScore {
+		ranges = []interface{}{startScore, "+inf", "-inf", endScore}
+	}
+
+	keysAndArgs := append([]interface{}{r.getDataKeysKey(), r.getDataKeyScoresKey()}, ranges...)
+	raws, err := redis.Strings(r.redisClient.Eval(ctx, luaListDataKeys, 2, keysAndArgs))
+	if err != nil {
+		return nil, fmt.Errorf("redis data key store list failed, err: %w", err)
+	}
+	if len(raws)&1 != 0 {
+		return nil, fmt.Errorf("invalid entity len: %d", len(raws))
+	}
+
+	dataKeys := make(map[string]struct{})
+	for i := 0; i < len(raws); i += 2 {
+		score, err := parseDataKeyScore(raws[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if csHash.InArc(score, startScore, endScore) {
+			dataKeys[raws[i]] = struct{}{}
+		}
+	}
+	return dataKeys, nil
+}
+
+// 解析数据key的score，兼容没有保存精确score的旧数据，此时只能使用zset中的double
+func parseDataKeyScore(raw string) (int64, error) {
+	if score, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return score, nil
+	}
+	score, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid data key score: %s", raw)
+	}
+	switch {
+	case score >= math.MaxInt64:
+		return math.MaxInt64, nil
+	case score <= math.MinInt64:
+		return math.MinInt64, nil
+	}
+	return int64(score), nil
+}
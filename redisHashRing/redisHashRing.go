@@ -11,11 +11,29 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/YShiJia/consistentHash"
 	"github.com/demdxx/gocast"
+	"github.com/gomodule/redigo/redis"
 	"github.com/xiaoxuxiansheng/redis_lock"
 )
 
+var (
+	_ csHash.BatchHashRing     = (*RedisHashRing)(nil)
+	_ csHash.WatchableHashRing = (*RedisHashRing)(nil)
+	_ csHash.SnapshotHashRing  = (*RedisHashRing)(nil)
+	_ csHash.ListableHashRing  = (*RedisHashRing)(nil)
+	_ csHash.RangeHashRing     = (*RedisHashRing)(nil)
+	_ csHash.StatefulHashRing  = (*RedisHashRing)(nil)
+	_ csHash.ChangeLogHashRing = (*RedisHashRing)(nil)
+)
+
+// 订阅断开后的重连间隔
+const watchRetryInterval = time.Second
+
 type RedisHashRing struct {
 	//哈希环版本，本地保存一份
 	version     int64
@@ -44,6 +62,21 @@ func (r *RedisHashRing) getTableVersionKey() string {
 	return fmt.Sprintf("redis:consistent_hash:ring:version:%s", r.key)
 }
 
+// 修改通知 channel name
+func (r *RedisHashRing) getChangeChannel() string {
+	return fmt.Sprintf("redis:consistent_hash:ring:change:%s", r.key)
+}
+
+// 最近一次逻辑操作的修改记录list，元素与修改通知的格式相同
+func (r *RedisHashRing) getChangesKey() string {
+	return fmt.Sprintf("redis:consistent_hash:ring:changes:%s", r.key)
+}
+
+// 最近一次逻辑操作的起始版本号
+func (r *RedisHashRing) getOperationStartKey() string {
+	return fmt.Sprintf("redis:consistent_hash:ring:operation_start:%s", r.key)
+}
+
 func (r *RedisHashRing) getNodeReplicaKey() string {
 	return fmt.Sprintf("redis:consistent_hash:ring:node:replica:%s", r.key)
 }
@@ -59,154 +92,205 @@ func (r *RedisHashRing) Unlock(ctx context.Context) error {
 }
 
 func (r *RedisHashRing) AddVirtualNode(ctx context.Context, score int64, nodeID string) (version int64, err error) {
-	hashScore, err := r.GetVirtualNode(ctx, score)
+	keysAndArgs := []interface{}{r.getRingKey(), r.getTableVersionKey(), r.getChangesKey(), r.getChangeChannel(), score, nodeID}
+	version, err = redis.Int64(r.redisClient.Eval(ctx, luaAddVirtualNode, 3, keysAndArgs))
 	if err != nil {
-		//数据不存在
-		if errors.Is(err, csHash.ErrVirtualNodeNotExists) {
-			hashScore = &csHash.HashScore{
-				Score:        score,
-				VirtualNodes: make([]csHash.VirtualNode, 0),
-			}
-		} else {
-			return 0, err
-		}
-	}
-	//如果数据已经存在，直接返回
-	for _, virtualNode := range hashScore.VirtualNodes {
-		if virtualNode.VirtualNodeID == nodeID {
-			return virtualNode.Version, nil
-		}
+		return 0, fmt.Errorf("redis ring add virtual node failed, err: %w", err)
 	}
+	//本地记录版本
+	r.version = max(version, r.version)
+	return version, nil
+}
 
-	if err = r.redisClient.ZRem(ctx, r.getRingKey(), score); err != nil {
-		return 0, fmt.Errorf("redis ring zrem failed, err: %w", err)
+func (r *RedisHashRing) RemoveVirtualNode(ctx context.Context, score int64, nodeID string) error {
+	keysAndArgs := []interface{}{r.getRingKey(), r.getTableVersionKey(), r.getChangesKey(), r.getChangeChannel(), score, nodeID}
+	version, err := redis.Int64(r.redisClient.Eval(ctx, luaRemoveVirtualNode, 3, keysAndArgs))
+	if err != nil {
+		return fmt.Errorf("redis ring remove virtual node failed, err: %w", err)
 	}
+	r.version = max(version, r.version)
+	return nil
+}
 
-	hashRingVersion, err := r.GetVersion(ctx)
+func (r *RedisHashRing) AddVirtualNodes(ctx context.Context, virtualNodes []csHash.VirtualNodeEntry) (version int64, err error) {
+	version, err = redis.Int64(r.redisClient.Eval(ctx, luaAddVirtualNodes, 3, r.getBatchKeysAndArgs(virtualNodes)))
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("redis ring add virtual nodes failed, err: %w", err)
 	}
-	r.version = max(gocast.ToInt64(hashRingVersion), r.version)
-
-	//TODO 后面想个办法解决一下数据溢出的问题，可以考虑使用英文进制，让字符串作为版本号
-	hashScore.VirtualNodes = append(hashScore.VirtualNodes, csHash.VirtualNode{
-		VirtualNodeID: nodeID,
-		Version:       r.version + 1,
-	})
+	r.version = max(version, r.version)
+	return version, nil
+}
 
-	hsnData, _ := json.Marshal(hashScore)
-	if err := r.redisClient.ZAdd(ctx, r.getRingKey(), score, string(hsnData)); err != nil {
-		return 0, fmt.Errorf("redis ring zadd failed, err: %w", err)
+func (r *RedisHashRing) RemoveVirtualNodes(ctx context.Context, virtualNodes []csHash.VirtualNodeEntry) (version int64, err error) {
+	version, err = redis.Int64(r.redisClient.Eval(ctx, luaRemoveVirtualNodes, 3, r.getBatchKeysAndArgs(virtualNodes)))
+	if err != nil {
+		return 0, fmt.Errorf("redis ring remove virtual nodes failed, err: %w", err)
 	}
-	//本地记录版本+1
-	r.version++
-	//更新redis 版本
-	r.SetVersion(ctx, r.version)
-
-	return r.version, nil
+	r.version = max(version, r.version)
+	return version, nil
 }
 
-func (r *RedisHashRing) RemoveVirtualNode(ctx context.Context, score int64, nodeID string) error {
-	hashScore, err := r.GetVirtualNode(ctx, score)
-	if err != nil {
-		//数据不存在
-		if errors.Is(err, csHash.ErrVirtualNodeNotExists) {
-			return nil
-		} else {
-			//真实报错
-			return err
-		}
-	}
-	index := 0
-	for ; index < len(hashScore.VirtualNodes) && hashScore.VirtualNodes[index].VirtualNodeID != nodeID; index++ {
-	}
-	if index == len(hashScore.VirtualNodes) {
-		return nil
+// 批量脚本的参数：哈希环zset、版本号、修改记录、修改通知channel，之后依次为score、虚拟节点id
+func (r *RedisHashRing) getBatchKeysAndArgs(virtualNodes []csHash.VirtualNodeEntry) []interface{} {
+	keysAndArgs := make([]interface{}, 0, 4+len(virtualNodes)<<1)
+	keysAndArgs = append(keysAndArgs, r.getRingKey(), r.getTableVersionKey(), r.getChangesKey(), r.getChangeChannel())
+	for _, virtualNode := range virtualNodes {
+		keysAndArgs = append(keysAndArgs, virtualNode.Score, virtualNode.VirtualNodeID)
 	}
+	return keysAndArgs
+}
 
-	//TODO 后续优化一下删除流程，不能让数据有删除了，但是没有上传的情况出现
-	if err = r.redisClient.ZRem(ctx, r.getRingKey(), score); err != nil {
-		return fmt.Errorf("redis ring zrem failed, err: %w", err)
+// 订阅哈希环修改通知，订阅连接断开时自动重连，ctx结束后关闭channel
+// 重连期间发布的通知会丢失，使用方可以通过GetVersion兜底
+func (r *RedisHashRing) Watch(ctx context.Context) (<-chan *csHash.RingChange, error) {
+	conn, err := r.redisClient.Subscribe(ctx, r.getChangeChannel())
+	if err != nil {
+		return nil, fmt.Errorf("redis ring subscribe failed, err: %w", err)
 	}
 
-	//只有一个节点，那就是需要删除的节点，后面无需更新数据
-	if len(hashScore.VirtualNodes) == 1 {
-		return nil
-	}
-	hashScore.VirtualNodes = append(hashScore.VirtualNodes[:index], hashScore.VirtualNodes[index+1:]...)
+	changes := make(chan *csHash.RingChange)
+	go func() {
+		defer close(changes)
+		for {
+			r.receiveChanges(ctx, conn, changes)
+			conn.Close()
+			//重新订阅，直到ctx结束
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(watchRetryInterval):
+				}
+				if conn, err = r.redisClient.Subscribe(ctx, r.getChangeChannel()); err == nil {
+					break
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
 
-	hsnData, _ := json.Marshal(hashScore)
-	return r.redisClient.ZAdd(ctx, r.getRingKey(), score, string(hsnData))
+// 持续接收修改通知，直到连接出错或ctx结束
+func (r *RedisHashRing) receiveChanges(ctx context.Context, conn *redis.PubSubConn, changes chan<- *csHash.RingChange) {
+	for {
+		switch msg := conn.ReceiveContext(ctx).(type) {
+		case error:
+			return
+		case redis.Message:
+			change := csHash.RingChange{}
+			if err := json.Unmarshal(msg.Data, &change); err != nil {
+				continue
+			}
+			select {
+			case changes <- &change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
 }
 
 func (r *RedisHashRing) GetVirtualNode(ctx context.Context, score int64) (hashScore *csHash.HashScore, err error) {
+	//64位score转换为double后可能与其他score相等，按成员中的精确score过滤
 	scoreEntities, err := r.redisClient.ZRangeByScore(ctx, r.getRingKey(), score, score)
 	if err != nil {
 		return nil, err
 	}
-	if len(scoreEntities) != 1 {
-		//不存在数据，直接返回
-		if len(scoreEntities) == 0 {
-			return nil, csHash.ErrVirtualNodeNotExists
+	for _, scoreEntity := range scoreEntities {
+		hs := csHash.HashScore{}
+		if err = json.Unmarshal([]byte(scoreEntity.Val), &hs); err != nil {
+			return nil, err
+		}
+		if hs.Score == score {
+			return &hs, nil
 		}
-		return nil, fmt.Errorf("invalid entity len: %d", len(scoreEntities))
 	}
+	return nil, csHash.ErrVirtualNodeNotExists
+}
 
-	hs := csHash.HashScore{}
-	if err = json.Unmarshal([]byte(scoreEntities[0].Val), &hs); err != nil {
-		return nil, err
+func (r *RedisHashRing) GetAllVirtualNodes(ctx context.Context) (hashScores []*csHash.HashScore, version int64, err error) {
+	keysAndArgs := []interface{}{r.getRingKey(), r.getTableVersionKey()}
+	raws, err := redis.Values(r.redisClient.Eval(ctx, luaGetAllVirtualNodes, 2, keysAndArgs))
+	if err != nil {
+		return nil, 0, fmt.Errorf("redis ring get all virtual nodes failed, err: %w", err)
+	}
+	if len(raws) == 0 {
+		return nil, 0, fmt.Errorf("invalid entity len: %d", len(raws))
+	}
+
+	version = gocast.ToInt64(raws[0])
+	members, err := redis.Strings(raws[1:], nil)
+	if err != nil {
+		return nil, 0, err
 	}
-	return &hs, nil
+	if hashScores, err = decodeHashScores(members); err != nil {
+		return nil, 0, err
+	}
+	return hashScores, version, nil
 }
 
 func (r *RedisHashRing) FindDataToVirtualNode(ctx context.Context, dataScore int64) (virtualNodeID string, err error) {
-	scoreEntity, err := r.redisClient.Ceiling(ctx, r.getRingKey(), dataScore)
-	//发生错误
-	if err != nil && !errors.Is(err, ErrScoreNotExist) {
+	keysAndArgs := []interface{}{r.getRingKey(), dataScore}
+	virtualNodeID, err = redis.String(r.redisClient.Eval(ctx, luaFindDataToVirtualNode, 1, keysAndArgs))
+	if err != nil {
+		//节点不存在
+		if errors.Is(err, redis.ErrNil) {
+			return "", csHash.ErrVirtualNodeNotExists
+		}
 		return "", err
 	}
-	hashScore := csHash.HashScore{}
-	//找到了数据
-	if scoreEntity != nil {
+	return virtualNodeID, nil
+}
 
-		if err := json.Unmarshal([]byte(scoreEntity.Val), &hashScore); err != nil {
-			return "", err
-		}
-		return hashScore.VirtualNodes[0].VirtualNodeID, nil
-	}
-	//寻找第一个score节点数据
-	scoreEntity, err = r.redisClient.FirstOrLast(ctx, r.getRingKey(), true)
+func (r *RedisHashRing) RangeVirtualNodes(ctx context.Context, startScore int64, limit int64) (hashScores []*csHash.HashScore, err error) {
+	keysAndArgs := []interface{}{r.getRingKey(), startScore, limit}
+	members, err := redis.Strings(r.redisClient.Eval(ctx, luaRangeVirtualNodes, 1, keysAndArgs))
 	if err != nil {
-		if errors.Is(err, ErrScoreNotExist) {
-			//节点不存在
-			return "", csHash.ErrVirtualNodeNotExists
-		} else {
-			return "", err
-		}
+		return nil, fmt.Errorf("redis ring range virtual nodes failed, err: %w", err)
 	}
-	if err := json.Unmarshal([]byte(scoreEntity.Val), &hashScore); err != nil {
-		return "", err
+	if hashScores, err = decodeHashScores(members); err != nil {
+		return nil, err
+	}
+	if int64(len(hashScores)) > limit {
+		hashScores = hashScores[:limit]
 	}
-	return hashScore.VirtualNodes[0].VirtualNodeID, nil
+	return hashScores, nil
+}
+
+// 解码zset成员并按精确score升序排列，zset只按double排序，64位score可能有多个成员对应同一个double
+func decodeHashScores(members []string) ([]*csHash.HashScore, error) {
+	hashScores := make([]*csHash.HashScore, 0, len(members))
+	for _, member := range members {
+		hs := csHash.HashScore{}
+		if err := json.Unmarshal([]byte(member), &hs); err != nil {
+			return nil, err
+		}
+		hashScores = append(hashScores, &hs)
+	}
+	sort.Slice(hashScores, func(i, j int) bool {
+		return hashScores[i].Score < hashScores[j].Score
+	})
+	return hashScores, nil
 }
 
 func (r *RedisHashRing) AddRealNode(ctx context.Context, nodeName string, replicas int64) (err error) {
-	if err = r.redisClient.HSet(ctx, r.getNodeReplicaKey(), nodeName, gocast.ToString(replicas)); err != nil {
+	keysAndArgs := []interface{}{r.getNodeReplicaKey(), nodeName, replicas}
+	if _, err = r.redisClient.Eval(ctx, luaAddRealNode, 1, keysAndArgs); err != nil {
 		return fmt.Errorf("redis ring add node to replica failed, err: %w", err)
 	}
 	return nil
 }
 
 func (r *RedisHashRing) GetRealNodes(ctx context.Context) (nodes map[string]int64, err error) {
-	res, err := r.redisClient.HGetAll(ctx, r.getNodeReplicaKey())
+	infos, err := r.GetRealNodeInfos(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("redis ring nodes hgetall failed, err: %w", err)
+		return nil, err
 	}
-	nodes = make(map[string]int64, len(res))
-	for k, v := range res {
-		nodes[k] = gocast.ToInt64(v)
+	nodes = make(map[string]int64, len(infos))
+	for nodeName, info := range infos {
+		nodes[nodeName] = info.Replicas
 	}
-	return nodes, err
+	return nodes, nil
 }
 
 func (r *RedisHashRing) GetRealNode(ctx context.Context, nodeName string) (replicas int64, err error) {
@@ -224,14 +308,98 @@ func (r *RedisHashRing) RemoveRealNode(ctx context.Context, nodeName string) (er
 	return nil
 }
 
+func (r *RedisHashRing) GetRealNodeInfos(ctx context.Context) (nodes map[string]csHash.RealNode, err error) {
+	res, err := r.redisClient.HGetAll(ctx, r.getNodeReplicaKey())
+	if err != nil {
+		return nil, fmt.Errorf("redis ring nodes hgetall failed, err: %w", err)
+	}
+	nodes = make(map[string]csHash.RealNode, len(res))
+	for k, v := range res {
+		node, err := parseRealNode(v)
+		if err != nil {
+			return nil, err
+		}
+		nodes[k] = node
+	}
+	return nodes, nil
+}
+
+func (r *RedisHashRing) SetRealNodeState(ctx context.Context, nodeName string, state csHash.NodeState) (err error) {
+	keysAndArgs := []interface{}{r.getNodeReplicaKey(), r.getTableVersionKey(), r.getChangesKey(), r.getChangeChannel(), nodeName, string(state)}
+	version, err := redis.Int64(r.redisClient.Eval(ctx, luaSetRealNodeState, 3, keysAndArgs))
+	if err != nil {
+		return fmt.Errorf("redis ring set node state failed, err: %w", err)
+	}
+	if version == 0 {
+		return csHash.ErrNodeNotExists
+	}
+	r.version = max(version, r.version)
+	return nil
+}
+
+// 解析真实节点信息，兼容只保存了映射数量的旧数据
+func parseRealNode(raw string) (csHash.RealNode, error) {
+	node := csHash.RealNode{}
+	if !strings.HasPrefix(raw, "{") {
+		node.Replicas = gocast.ToInt64(raw)
+		node.State = csHash.NodeStateActive
+		return node, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &node); err != nil {
+		return node, err
+	}
+	return node, nil
+}
+
+func (r *RedisHashRing) BeginOperation(ctx context.Context) (err error) {
+	keysAndArgs := []interface{}{r.getTableVersionKey(), r.getOperationStartKey(), r.getChangesKey()}
+	if _, err = r.redisClient.Eval(ctx, luaBeginOperation, 3, keysAndArgs); err != nil {
+		return fmt.Errorf("redis ring begin operation failed, err: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisHashRing) GetLastOperation(ctx context.Context) (operation *csHash.RingOperation, err error) {
+	keysAndArgs := []interface{}{r.getOperationStartKey(), r.getChangesKey()}
+	raws, err := redis.Values(r.redisClient.Eval(ctx, luaGetLastOperation, 2, keysAndArgs))
+	if err != nil {
+		return nil, fmt.Errorf("redis ring get last operation failed, err: %w", err)
+	}
+	if len(raws) == 0 {
+		return nil, fmt.Errorf("invalid entity len: %d", len(raws))
+	}
+
+	operation = &csHash.RingOperation{StartVersion: gocast.ToInt64(raws[0])}
+	payloads, err := redis.Strings(raws[1:], nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, payload := range payloads {
+		change := &csHash.RingChange{}
+		if err = json.Unmarshal([]byte(payload), change); err != nil {
+			return nil, fmt.Errorf("redis ring parse change failed, err: %w", err)
+		}
+		operation.Changes = append(operation.Changes, change)
+	}
+	return operation, nil
+}
+
 func (r *RedisHashRing) GetVersion(ctx context.Context) (version int64, err error) {
 	versionStr, err := r.redisClient.Get(ctx, r.getTableVersionKey())
 	if err != nil {
+		//哈希环还未修改过
+		if errors.Is(err, redis.ErrNil) {
+			return 0, nil
+		}
 		return 0, err
 	}
 	return gocast.ToInt64(versionStr), nil
 }
 
 func (r *RedisHashRing) SetVersion(ctx context.Context, version int64) (err error) {
-	return r.redisClient.Set(ctx, r.getTableVersionKey(), fmt.Sprintf("%v", r.version))
+	if err = r.redisClient.Set(ctx, r.getTableVersionKey(), fmt.Sprintf("%v", version)); err != nil {
+		return err
+	}
+	r.version = version
+	return nil
 }
@@ -0,0 +1,104 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 19:21:48
+ */
+
+package redisHashRing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/YShiJia/consistentHash"
+	"github.com/gomodule/redigo/redis"
+)
+
+// 快照hash，field为版本号，value为快照json
+func (r *RedisHashRing) getSnapshotKey() string {
+	return fmt.Sprintf("redis:consistent_hash:ring:snapshot:%s", r.key)
+}
+
+func (r *RedisHashRing) SaveSnapshot(ctx context.Context, snapshot *csHash.RingSnapshot) (err error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	//快照不可变，同一版本只保存一次
+	if _, err = r.redisClient.HSetNX(ctx, r.getSnapshotKey(), strconv.FormatInt(snapshot.Version, 10), string(data)); err != nil {
+		return fmt.Errorf("redis ring save snapshot failed, err: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisHashRing) GetSnapshot(ctx context.Context, version int64) (snapshot *csHash.RingSnapshot, err error) {
+	raw, err := r.redisClient.HGet(ctx, r.getSnapshotKey(), strconv.FormatInt(version, 10))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return nil, csHash.ErrSnapshotNotExists
+		}
+		return nil, err
+	}
+	snapshot = &csHash.RingSnapshot{}
+	if err = json.Unmarshal([]byte(raw), snapshot); err != nil {
+		return nil, fmt.Errorf("redis ring parse snapshot failed, err: %w", err)
+	}
+	return snapshot, nil
+}
+
+func (r *RedisHashRing) GetSnapshotVersions(ctx context.Context) (versions []int64, err error) {
+	fields, err := r.redisClient.HKeys(ctx, r.getSnapshotKey())
+	if err != nil {
+		return nil, err
+	}
+	versions = make([]int64, 0, len(fields))
+	for _, field := range fields {
+		version, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] < versions[j]
+	})
+	return versions, nil
+}
+
+func (r *RedisHashRing) DeleteSnapshot(ctx context.Context, version int64) (err error) {
+	return r.redisClient.HDel(ctx, r.getSnapshotKey(), strconv.FormatInt(version, 10))
+}
+
+func (r *RedisHashRing) ResetRing(ctx context.Context, snapshot *csHash.RingSnapshot) (version int64, err error) {
+	keysAndArgs := make([]interface{}, 0, 6+len(snapshot.HashScores)<<1+len(snapshot.RealNodes)<<1)
+	keysAndArgs = append(keysAndArgs, r.getRingKey(), r.getTableVersionKey(), r.getChangesKey(), r.getNodeReplicaKey(),
+		r.getChangeChannel(), len(snapshot.HashScores))
+	for _, hashScore := range snapshot.HashScores {
+		data, err := json.Marshal(hashScore)
+		if err != nil {
+			return 0, err
+		}
+		keysAndArgs = append(keysAndArgs, hashScore.Score, string(data))
+	}
+	for nodeName, node := range snapshot.RealNodes {
+		if node.State == "" {
+			node.State = csHash.NodeStateActive
+		}
+		data, err := json.Marshal(node)
+		if err != nil {
+			return 0, err
+		}
+		keysAndArgs = append(keysAndArgs, nodeName, string(data))
+	}
+
+	version, err = redis.Int64(r.redisClient.Eval(ctx, luaResetRing, 4, keysAndArgs))
+	if err != nil {
+		return 0, fmt.Errorf("redis ring reset failed, err: %w", err)
+	}
+	r.version = max(version, r.version)
+	return version, nil
+}
@@ -0,0 +1,174 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 20:16:48
+ */
+
+package csHash
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+var _ Router = (*Rendezvous)(nil)
+
+// Rendezvous（最高随机权重，HRW）哈希，节点较少时比哈希环更均匀
+// 每个节点对数据打分，得分最高的节点负责该数据，删除节点时只有该节点上的数据迁移
+//
+// 节点列表保存在哈希环的真实节点列表中，映射数量保存的是节点权重，哈希环上不写入虚拟节点
+// 选项中只有WithLockExpireSeconds和WithHealthChecker生效
+type Rendezvous struct {
+	hashRing  HashRing
+	encryptor HashEncryptor
+	opts      ConsistentHashOptions
+}
+
+func NewRendezvous(hashRing HashRing, encryptor HashEncryptor, opts ...ConsistentHashOption) *Rendezvous {
+	r := Rendezvous{
+		hashRing:  hashRing,
+		encryptor: encryptor,
+	}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	r.opts.repair()
+	return &r
+}
+
+// 加权得分 -weight / ln(u)，u为节点和数据的哈希值映射到 (0, 1) 区间
+// 各节点胜出的概率与权重成正比
+func (r *Rendezvous) score(nodeName, dataKey string, weight int64) float64 {
+	hash := r.encryptor.Encrypt(nodeName + "_" + dataKey)
+	u := (float64(uint32(hash)%hashSpace) + 0.5) / hashSpace
+	return -float64(weight) / math.Log(u)
+}
+
+// 添加节点，权重小于1时按1处理
+func (r *Rendezvous) AddNode(ctx context.Context, nodeName string, weight int64) error {
+	if err := r.hashRing.Lock(ctx, r.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer r.hashRing.Unlock(ctx)
+
+	nodes, err := r.hashRing.GetRealNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if replicas := nodes[nodeName]; replicas > 0 {
+		return ErrNodeAlreadyExists
+	}
+	return r.hashRing.AddRealNode(ctx, nodeName, max(weight, 1))
+}
+
+func (r *Rendezvous) RemoveNode(ctx context.Context, nodeName string) error {
+	if err := r.hashRing.Lock(ctx, r.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer r.hashRing.Unlock(ctx)
+
+	nodes, err := r.hashRing.GetRealNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := nodes[nodeName]; !ok {
+		return ErrNodeNotExists
+	}
+	return r.hashRing.RemoveRealNode(ctx, nodeName)
+}
+
+// 修改节点权重，权重小于1时按1处理
+func (r *Rendezvous) UpdateNodeWeight(ctx context.Context, nodeName string, weight int64) error {
+	if err := r.hashRing.Lock(ctx, r.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer r.hashRing.Unlock(ctx)
+
+	nodes, err := r.hashRing.GetRealNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := nodes[nodeName]; !ok {
+		return ErrNodeNotExists
+	}
+	return r.hashRing.AddRealNode(ctx, nodeName, max(weight, 1))
+}
+
+// 查找得分最高的可用节点用于读取，跳过不可用、不健康的节点
+// 与ConsistentHash.GetNode一致，不跳过下线中的节点，写入新数据请使用GetNodeForWrite
+func (r *Rendezvous) GetNode(ctx context.Context, dataKey string) (nodeName string, err error) {
+	return r.getNode(ctx, dataKey, false)
+}
+
+// 查找写入新数据的节点，与GetNode相比还会跳过下线中的节点
+func (r *Rendezvous) GetNodeForWrite(ctx context.Context, dataKey string) (nodeName string, err error) {
+	return r.getNode(ctx, dataKey, true)
+}
+
+func (r *Rendezvous) getNode(ctx context.Context, dataKey string, forWrite bool) (nodeName string, err error) {
+	nodeNames, err := r.getNodes(ctx, dataKey, 1, forWrite)
+	if err != nil {
+		return "", err
+	}
+	if len(nodeNames) == 0 {
+		return "", ErrNoAvailableNode
+	}
+	return nodeNames[0], nil
+}
+
+// 按得分从高到低返回n个不同的可用节点用于读取，跳过不可用、不健康的节点
+// 可用节点不足n个时返回全部可用节点，没有任何节点时返回ErrNodeNotExists
+// 与GetNode一样不跳过下线中的节点，写入新数据请使用GetNodesForWrite
+func (r *Rendezvous) GetNodes(ctx context.Context, dataKey string, n int) (nodeNames []string, err error) {
+	return r.getNodes(ctx, dataKey, n, false)
+}
+
+// 查找写入新数据的n个不同的节点，与GetNodes相比还会跳过下线中的节点
+func (r *Rendezvous) GetNodesForWrite(ctx context.Context, dataKey string, n int) (nodeNames []string, err error) {
+	return r.getNodes(ctx, dataKey, n, true)
+}
+
+func (r *Rendezvous) getNodes(ctx context.Context, dataKey string, n int, forWrite bool) (nodeNames []string, err error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+	nodes, err := readRealNodeInfos(ctx, r.hashRing)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, ErrNodeNotExists
+	}
+
+	type nodeScore struct {
+		name  string
+		score float64
+	}
+	scores := make([]nodeScore, 0, len(nodes))
+	for name, node := range nodes {
+		if !node.usable(forWrite) {
+			continue
+		}
+		if r.opts.healthChecker != nil && !r.opts.healthChecker.IsHealthy(ctx, name) {
+			continue
+		}
+		scores = append(scores, nodeScore{name: name, score: r.score(name, dataKey, node.Replicas)})
+	}
+	//得分相同时按节点名排序，保证结果稳定
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score != scores[j].score {
+			return scores[i].score > scores[j].score
+		}
+		return scores[i].name < scores[j].name
+	})
+
+	nodeNames = make([]string, 0, min(n, len(scores)))
+	for i := 0; i < len(scores) && i < n; i++ {
+		nodeNames = append(nodeNames, scores[i].name)
+	}
+	return nodeNames, nil
+}
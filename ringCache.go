@@ -0,0 +1,197 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 13:21:08
+ */
+
+package csHash
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 某个版本哈希环的本地只读副本
+type ringView struct {
+	version    int64
+	hashScores []*HashScore
+	nodes      map[string]RealNode
+}
+
+func newRingView(version int64, hashScores []*HashScore, nodes map[string]RealNode) *ringView {
+	view := ringView{
+		version:    version,
+		hashScores: make([]*HashScore, 0, len(hashScores)),
+		nodes:      nodes,
+	}
+	//忽略没有虚拟节点的score
+	for _, hashScore := range hashScores {
+		if hashScore != nil && len(hashScore.VirtualNodes) > 0 {
+			view.hashScores = append(view.hashScores, hashScore)
+		}
+	}
+	sort.Slice(view.hashScores, func(i, j int) bool {
+		return view.hashScores[i].Score < view.hashScores[j].Score
+	})
+	return &view
+}
+
+// 顺时针查找第一个score大于等于dataScore的下标，到达环尾则回到环首
+func (v *ringView) ceilingIndex(dataScore int64) int {
+	index := sort.Search(len(v.hashScores), func(i int) bool {
+		return v.hashScores[i].Score >= dataScore
+	})
+	if index == len(v.hashScores) {
+		index = 0
+	}
+	return index
+}
+
+// 根据数据score，找到对应的虚拟节点
+func (v *ringView) findDataToVirtualNode(dataScore int64) (string, error) {
+	if len(v.hashScores) == 0 {
+		return "", ErrVirtualNodeNotExists
+	}
+	return v.hashScores[v.ceilingIndex(dataScore)].VirtualNodes[0].VirtualNodeID, nil
+}
+
+// 根据数据score顺时针查找第一个可用的真实节点，写入时还会跳过下线中的节点，没有可用节点时返回ErrNoAvailableNode
+func (v *ringView) lookup(dataScore int64, forWrite bool) (string, error) {
+	if len(v.hashScores) == 0 {
+		return "", ErrVirtualNodeNotExists
+	}
+	start := v.ceilingIndex(dataScore)
+	for i := 0; i < len(v.hashScores); i++ {
+		nodeName, _, err := parseVirtualNodeID(v.hashScores[(start+i)%len(v.hashScores)].VirtualNodes[0].VirtualNodeID)
+		if err != nil {
+			return "", err
+		}
+		if v.nodes[nodeName].usable(forWrite) {
+			return nodeName, nil
+		}
+	}
+	return "", ErrNoAvailableNode
+}
+
+// 某个版本哈希环的真实节点信息
+type versionedNodeInfos struct {
+	version int64
+	nodes   map[string]RealNode
+}
+
+// 按哈希环版本号缓存真实节点信息，未开启本地缓存时避免每次查找都读取整个真实节点列表
+// 修改节点状态、增删虚拟节点都会使版本号加一，版本号不变时只需要读取一次版本号
+type nodeInfoCache struct {
+	hashRing HashRing
+	infos    atomic.Pointer[versionedNodeInfos]
+}
+
+func newNodeInfoCache(hashRing HashRing) *nodeInfoCache {
+	return &nodeInfoCache{hashRing: hashRing}
+}
+
+func (n *nodeInfoCache) get(ctx context.Context) (map[string]RealNode, error) {
+	// 版本号在真实节点列表之前读取，期间发生的修改会让下次查找时版本号不一致，从而再次读取
+	version, err := n.hashRing.GetVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if infos := n.infos.Load(); infos != nil && infos.version == version {
+		return infos.nodes, nil
+	}
+	nodes, err := readRealNodeInfos(ctx, n.hashRing)
+	if err != nil {
+		return nil, err
+	}
+	n.infos.Store(&versionedNodeInfos{version: version, nodes: nodes})
+	return nodes, nil
+}
+
+// 丢弃缓存，下次查找时重新读取
+func (n *nodeInfoCache) reset() {
+	n.infos.Store(nil)
+}
+
+// 读取整个哈希环的虚拟节点以及版本号
+type virtualNodesLoader func(ctx context.Context) (hashScores []*HashScore, version int64, err error)
+
+// 哈希环本地缓存，根据哈希环版本号判断是否需要重新加载
+type ringCache struct {
+	hashRing         HashRing
+	loadVirtualNodes virtualNodesLoader
+	view             atomic.Pointer[ringView]
+	// 保证同一时间只有一个加载过程
+	mu sync.Mutex
+}
+
+func newRingCache(hashRing HashRing, loadVirtualNodes virtualNodesLoader) *ringCache {
+	return &ringCache{
+		hashRing:         hashRing,
+		loadVirtualNodes: loadVirtualNodes,
+	}
+}
+
+// 获取本地副本，尚未加载时同步加载
+func (r *ringCache) get(ctx context.Context) (*ringView, error) {
+	if view := r.view.Load(); view != nil {
+		return view, nil
+	}
+	return r.reload(ctx, -1)
+}
+
+// 哈希环版本号发生变化时重新加载
+func (r *ringCache) refresh(ctx context.Context) error {
+	version, err := r.hashRing.GetVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if view := r.view.Load(); view != nil && view.version == version {
+		return nil
+	}
+	_, err = r.reload(ctx, version)
+	return err
+}
+
+// 加载整个哈希环，本地副本的版本号已经等于version时不再加载，version < 0时只要已有本地副本就不再加载
+func (r *ringCache) reload(ctx context.Context, version int64) (*ringView, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	//等锁期间其他协程已经完成加载
+	if view := r.view.Load(); view != nil && (version < 0 || view.version == version) {
+		return view, nil
+	}
+
+	hashScores, ringVersion, err := r.loadVirtualNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 真实节点列表在哈希环之后读取，期间发生的修改会让下次刷新时版本号不一致，从而再次加载
+	nodes, err := readRealNodeInfos(ctx, r.hashRing)
+	if err != nil {
+		return nil, err
+	}
+	view := newRingView(ringVersion, hashScores, nodes)
+	r.view.Store(view)
+	return view, nil
+}
+
+// 按固定间隔轮询哈希环版本号，直到ctx结束
+func (r *ringCache) poll(ctx context.Context, interval time.Duration, logger Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.refresh(ctx); err != nil && logger != nil {
+			logger.Warn("refresh ring cache failed", "err", err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 14:20:52
+ */
+
+package csHash
+
+import "context"
+
+type RingChangeType string
+
+const (
+	RingChangeAdd    RingChangeType = "add"
+	RingChangeRemove RingChangeType = "remove"
+	// 真实节点状态变化，只有NodeName和State有效
+	RingChangeState RingChangeType = "state"
+	// 整个哈希环被替换（例如回滚到快照），只有Version有效
+	RingChangeReset RingChangeType = "reset"
+)
+
+// 哈希环的一次修改，由HashRing在修改完成后发布
+type RingChange struct {
+	// 修改之后的版本号
+	Version      int64              `json:"version"`
+	Type         RingChangeType     `json:"type"`
+	VirtualNodes []VirtualNodeEntry `json:"virtual_nodes"`
+	NodeName     string             `json:"node_name,omitempty"`
+	State        NodeState          `json:"state,omitempty"`
+}
+
+// 每次逻辑操作最多保留的修改记录数，超出后丢弃最早的记录
+const MaxOperationChanges = 4096
+
+// 一次逻辑操作及其包含的修改，例如DrainNode会逐个删除虚拟节点，整个下线过程是一次逻辑操作
+type RingOperation struct {
+	// 操作开始之前的版本号
+	StartVersion int64 `json:"start_version"`
+	// 操作开始之后的修改，按版本号升序排列
+	Changes []*RingChange `json:"changes"`
+}
+
+// 支持记录修改历史的哈希环，GetNodeWithHistory依赖该接口
+type ChangeLogHashRing interface {
+	HashRing
+
+	// 开始一次新的逻辑操作：以当前版本号作为起始版本号，并清空之前的修改记录，之后的修改都记入这次操作
+	BeginOperation(ctx context.Context) (err error)
+	// 获取最近一次逻辑操作，从未开始过逻辑操作时起始版本号为0
+	GetLastOperation(ctx context.Context) (operation *RingOperation, err error)
+}
+
+// 支持订阅修改通知的哈希环
+type WatchableHashRing interface {
+	HashRing
+
+	// 订阅哈希环的修改，ctx结束后关闭channel
+	Watch(ctx context.Context) (<-chan *RingChange, error)
+}
+
+type RingEventType string
+
+const (
+	RingEventNodeAdded        RingEventType = "node_added"
+	RingEventNodeRemoved      RingEventType = "node_removed"
+	RingEventNodeStateChanged RingEventType = "node_state_changed"
+	// 整个哈希环被替换，NodeName为空，需要重新读取真实节点列表
+	RingEventRingReset RingEventType = "ring_reset"
+)
+
+// 真实节点维度的哈希环变更事件
+type RingEvent struct {
+	// 变更之后的哈希环版本号
+	Version  int64         `json:"version"`
+	Type     RingEventType `json:"type"`
+	NodeName string        `json:"node_name"`
+	// 节点状态变化事件中的新状态
+	State NodeState `json:"state,omitempty"`
+}
+
+// 订阅哈希环变更事件，哈希环需要实现WatchableHashRing，ctx结束后关闭channel
+// 同一次修改涉及多个真实节点时，按真实节点拆分成多个事件
+func (c *ConsistentHash) Watch(ctx context.Context) (<-chan RingEvent, error) {
+	watchableRing, ok := c.hashRing.(WatchableHashRing)
+	if !ok {
+		return nil, ErrWatchNotSupported
+	}
+	changes, err := watchableRing.Watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan RingEvent)
+	go func() {
+		defer close(events)
+		for change := range changes {
+			for _, event := range toRingEvents(change) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// 将虚拟节点维度的修改转换为真实节点维度的事件，保持真实节点首次出现的顺序
+func toRingEvents(change *RingChange) []RingEvent {
+	if change.Type == RingChangeReset {
+		return []RingEvent{{
+			Version: change.Version,
+			Type:    RingEventRingReset,
+		}}
+	}
+	if change.Type == RingChangeState {
+		return []RingEvent{{
+			Version:  change.Version,
+			Type:     RingEventNodeStateChanged,
+			NodeName: change.NodeName,
+			State:    change.State,
+		}}
+	}
+
+	eventType := RingEventNodeAdded
+	if change.Type == RingChangeRemove {
+		eventType = RingEventNodeRemoved
+	}
+
+	events := make([]RingEvent, 0)
+	seen := make(map[string]struct{})
+	for _, virtualNode := range change.VirtualNodes {
+		nodeName, _, err := parseVirtualNodeID(virtualNode.VirtualNodeID)
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[nodeName]; ok {
+			continue
+		}
+		seen[nodeName] = struct{}{}
+		events = append(events, RingEvent{
+			Version:  change.Version,
+			Type:     eventType,
+			NodeName: nodeName,
+		})
+	}
+	return events
+}
+
+// 订阅哈希环修改，收到修改后立即刷新本地缓存，直到ctx结束
+func (r *ringCache) watch(ctx context.Context, watchableRing WatchableHashRing, logger Logger) {
+	changes, err := watchableRing.Watch(ctx)
+	if err != nil {
+		if logger != nil {
+			logger.Warn("watch ring changes failed", "err", err)
+		}
+		return
+	}
+	for change := range changes {
+		if _, err := r.reload(ctx, change.Version); err != nil && logger != nil {
+			logger.Warn("refresh ring cache failed", "err", err)
+		}
+	}
+}
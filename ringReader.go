@@ -0,0 +1,108 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-20 10:14:52
+ */
+
+package csHash
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// 读取整个哈希环以及版本号
+// 哈希环未实现ListableHashRing时，根据真实节点列表重新计算所有虚拟节点，读取前后版本号不一致时重新读取
+func (c *ConsistentHash) getAllVirtualNodes(ctx context.Context) ([]*HashScore, int64, error) {
+	if listableRing, ok := c.hashRing.(ListableHashRing); ok {
+		return listableRing.GetAllVirtualNodes(ctx)
+	}
+	for i := 0; i < historyRetryTimes; i++ {
+		version, err := c.hashRing.GetVersion(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		hashScores, err := c.rebuildVirtualNodes(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		latestVersion, err := c.hashRing.GetVersion(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		if latestVersion == version {
+			return hashScores, version, nil
+		}
+	}
+	return nil, 0, ErrRingChanging
+}
+
+// 按真实节点的映射数量计算所有虚拟节点，按score升序排列
+// 虚拟节点的版本号无法得知，统一为0；多个虚拟节点落在同一score上时读取该score，以哈希环上的先后顺序为准
+func (c *ConsistentHash) rebuildVirtualNodes(ctx context.Context) ([]*HashScore, error) {
+	replicas, err := c.hashRing.GetRealNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]RealNode, len(replicas))
+	for nodeName, nodeReplicas := range replicas {
+		nodes[nodeName] = RealNode{Replicas: nodeReplicas}
+	}
+
+	byScore := make(map[int64]*HashScore)
+	for nodeName, count := range c.virtualNodeCounts(nodes) {
+		for _, entry := range c.getVirtualNodeEntries(nodeName, 1, count) {
+			hashScore := byScore[entry.Score]
+			if hashScore == nil {
+				hashScore = &HashScore{Score: entry.Score}
+				byScore[entry.Score] = hashScore
+			}
+			hashScore.VirtualNodes = append(hashScore.VirtualNodes, VirtualNode{VirtualNodeID: entry.VirtualNodeID})
+		}
+	}
+
+	hashScores := make([]*HashScore, 0, len(byScore))
+	for score, hashScore := range byScore {
+		if len(hashScore.VirtualNodes) > 1 {
+			actual, err := c.hashRing.GetVirtualNode(ctx, score)
+			if err != nil && !errors.Is(err, ErrVirtualNodeNotExists) {
+				return nil, err
+			}
+			if actual == nil || len(actual.VirtualNodes) == 0 {
+				continue
+			}
+			hashScore = actual
+		}
+		hashScores = append(hashScores, hashScore)
+	}
+	sort.Slice(hashScores, func(i, j int) bool {
+		return hashScores[i].Score < hashScores[j].Score
+	})
+	return hashScores, nil
+}
+
+// 读取真实节点信息，哈希环未实现StatefulHashRing时所有节点的状态均为正常
+func readRealNodeInfos(ctx context.Context, hashRing HashRing) (map[string]RealNode, error) {
+	if statefulRing, ok := hashRing.(StatefulHashRing); ok {
+		return statefulRing.GetRealNodeInfos(ctx)
+	}
+	replicas, err := hashRing.GetRealNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]RealNode, len(replicas))
+	for nodeName, nodeReplicas := range replicas {
+		nodes[nodeName] = RealNode{Replicas: nodeReplicas, State: NodeStateActive}
+	}
+	return nodes, nil
+}
+
+// 修改真实节点状态，哈希环未实现StatefulHashRing时返回ErrRingNotSupported
+func setRealNodeState(ctx context.Context, hashRing HashRing, nodeName string, state NodeState) error {
+	statefulRing, ok := hashRing.(StatefulHashRing)
+	if !ok {
+		return ErrRingNotSupported
+	}
+	return statefulRing.SetRealNodeState(ctx, nodeName, state)
+}
@@ -0,0 +1,19 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 22:41:36
+ */
+
+package csHash
+
+import "context"
+
+var _ Router = (*ConsistentHash)(nil)
+
+// 数据路由的查找接口，进程内的ConsistentHash和远程客户端都实现了该接口，调用方可以无缝切换
+type Router interface {
+	// 查找数据所属的节点
+	GetNode(ctx context.Context, dataKey string) (nodeName string, err error)
+	// 查找数据所属的n个不同的节点，可用节点不足n个时返回全部可用节点
+	GetNodes(ctx context.Context, dataKey string, n int) (nodeNames []string, err error)
+}
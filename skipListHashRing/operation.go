@@ -6,8 +6,195 @@
 
 package skipHashRing
 
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	csHash "github.com/YShiJia/consistentHash"
+)
+
+// 跳表节点，每个score对应一个节点，同一score上可以有多个虚拟节点，首元素生效
 type virtualNode struct {
 	score  int64
-	nodeID []string
+	nodeID []csHash.VirtualNode
 	next   []*virtualNode
 }
+
+func newVirtualNode(score int64, level int) *virtualNode {
+	return &virtualNode{
+		score: score,
+		next:  make([]*virtualNode, level),
+	}
+}
+
+// 随机生成节点层数，每升一层的概率为1/2
+func (s *SkipListHashRing) randomLevel() int {
+	level := 1
+	for level < s.opts.maxLevel && rand.Intn(2) == 0 {
+		level++
+	}
+	return level
+}
+
+// 返回每一层中score严格小于目标score的最后一个节点
+func (s *SkipListHashRing) findPrevNodes(score int64) []*virtualNode {
+	prevNodes := make([]*virtualNode, s.opts.maxLevel)
+	cur := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for cur.next[i] != nil && cur.next[i].score < score {
+			cur = cur.next[i]
+		}
+		prevNodes[i] = cur
+	}
+	return prevNodes
+}
+
+// 返回score大于等于目标score的第一个节点，不存在返回nil
+func (s *SkipListHashRing) ceiling(score int64) *virtualNode {
+	cur := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for cur.next[i] != nil && cur.next[i].score < score {
+			cur = cur.next[i]
+		}
+	}
+	return cur.next[0]
+}
+
+// 返回score等于目标score的节点，不存在返回nil
+func (s *SkipListHashRing) search(score int64) *virtualNode {
+	node := s.ceiling(score)
+	if node == nil || node.score != score {
+		return nil
+	}
+	return node
+}
+
+// 返回第一个节点，跳表为空返回nil
+func (s *SkipListHashRing) first() *virtualNode {
+	return s.head.next[0]
+}
+
+// 插入score节点，已存在则直接返回已有节点
+func (s *SkipListHashRing) insert(score int64) *virtualNode {
+	prevNodes := s.findPrevNodes(score)
+	if next := prevNodes[0].next[0]; next != nil && next.score == score {
+		return next
+	}
+
+	level := s.randomLevel()
+	for i := s.level; i < level; i++ {
+		prevNodes[i] = s.head
+	}
+	s.level = max(s.level, level)
+
+	node := newVirtualNode(score, level)
+	for i := 0; i < level; i++ {
+		node.next[i] = prevNodes[i].next[i]
+		prevNodes[i].next[i] = node
+	}
+	return node
+}
+
+// 删除score节点，不存在直接返回
+func (s *SkipListHashRing) delete(score int64) {
+	prevNodes := s.findPrevNodes(score)
+	node := prevNodes[0].next[0]
+	if node == nil || node.score != score {
+		return
+	}
+	for i := 0; i < len(node.next); i++ {
+		prevNodes[i].next[i] = node.next[i]
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+}
+
+// 在score上追加虚拟节点，已存在返回false
+func (s *SkipListHashRing) addVirtualNode(score int64, nodeID string, version int64) bool {
+	node := s.insert(score)
+	for _, virtualNode := range node.nodeID {
+		if virtualNode.VirtualNodeID == nodeID {
+			return false
+		}
+	}
+	node.nodeID = append(node.nodeID, csHash.VirtualNode{
+		VirtualNodeID: nodeID,
+		Version:       version,
+	})
+	s.virtualNodeNum++
+	return true
+}
+
+// 删除score上的虚拟节点，score上没有虚拟节点时删除score，不存在返回false
+func (s *SkipListHashRing) removeVirtualNode(score int64, nodeID string) bool {
+	node := s.search(score)
+	if node == nil {
+		return false
+	}
+	index := 0
+	for ; index < len(node.nodeID) && node.nodeID[index].VirtualNodeID != nodeID; index++ {
+	}
+	if index == len(node.nodeID) {
+		return false
+	}
+
+	s.virtualNodeNum--
+	//只有一个节点，直接删除score
+	if len(node.nodeID) == 1 {
+		s.delete(score)
+		return true
+	}
+	node.nodeID = append(node.nodeID[:index], node.nodeID[index+1:]...)
+	return true
+}
+
+// 订阅者，修改通知先进入队列，再由独立协程按顺序发送，慢订阅者不会阻塞哈希环的修改
+type watcher struct {
+	mu      sync.Mutex
+	changes []*csHash.RingChange
+	// 队列中有新通知
+	notify chan struct{}
+}
+
+func newWatcher() *watcher {
+	return &watcher{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (w *watcher) push(change *csHash.RingChange) {
+	w.mu.Lock()
+	w.changes = append(w.changes, change)
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// 将队列中的通知依次发送到out，直到ctx结束
+func (w *watcher) run(ctx context.Context, out chan<- *csHash.RingChange) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.notify:
+		}
+
+		w.mu.Lock()
+		changes := w.changes
+		w.changes = nil
+		w.mu.Unlock()
+
+		for _, change := range changes {
+			select {
+			case out <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -5,3 +5,33 @@
  */
 
 package skipHashRing
+
+const (
+	// 默认跳表最大层数
+	DefaultMaxLevel = 16
+	// 跳表层数上限
+	MaxLevelLimit = 32
+)
+
+type SkipListHashRingOptions struct {
+	// 跳表最大层数
+	maxLevel int
+}
+
+type SkipListHashRingOption func(opts *SkipListHashRingOptions)
+
+// maxLevel 跳表最大层数，范围为[1,32]，默认16
+func WithMaxLevel(maxLevel int) SkipListHashRingOption {
+	return func(opts *SkipListHashRingOptions) {
+		opts.maxLevel = maxLevel
+	}
+}
+
+func repairSkipListHashRing(opts *SkipListHashRingOptions) {
+	switch {
+	case opts.maxLevel <= 0:
+		opts.maxLevel = DefaultMaxLevel
+	case opts.maxLevel > MaxLevelLimit:
+		opts.maxLevel = MaxLevelLimit
+	}
+}
@@ -8,74 +8,403 @@ package skipHashRing
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
+
 	csHash "github.com/YShiJia/consistentHash"
 )
 
-type skipListHashRing struct {
+var ErrLockNotHeld = errors.New("skip list hash ring lock not held")
+
+// 锁轮询间隔
+const lockRetryInterval = 10 * time.Millisecond
+
+var (
+	_ csHash.BatchHashRing     = (*SkipListHashRing)(nil)
+	_ csHash.WatchableHashRing = (*SkipListHashRing)(nil)
+	_ csHash.SnapshotHashRing  = (*SkipListHashRing)(nil)
+	_ csHash.ListableHashRing  = (*SkipListHashRing)(nil)
+	_ csHash.RangeHashRing     = (*SkipListHashRing)(nil)
+	_ csHash.StatefulHashRing  = (*SkipListHashRing)(nil)
+	_ csHash.ChangeLogHashRing = (*SkipListHashRing)(nil)
+)
+
+// 基于跳表实现的进程内哈希环，适用于单进程服务和单元测试
+type SkipListHashRing struct {
+	opts SkipListHashRingOptions
+
+	// 保护哈希环数据
+	mu      sync.RWMutex
 	version int64
 	head    *virtualNode
+	level   int
 	// 每个节点对应的虚拟节点个数
-	nodeNum        map[string]int64
+	nodeNum map[string]int64
+	// 每个节点的状态
+	nodeState      map[string]csHash.NodeState
 	virtualNodeNum int64
-	RealNodeNum    int64
+	// 最近一次逻辑操作的起始版本号和修改记录
+	operationStart int64
+	changes        []*csHash.RingChange
+	// 按版本号保存的快照
+	snapshots map[int64]*csHash.RingSnapshot
+
+	// 保护哈希环锁的状态
+	lockMu       sync.Mutex
+	locked       bool
+	lockExpireAt time.Time
+
+	// 保护订阅者列表
+	watchMu  sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+func NewSkipListHashRing(opts ...SkipListHashRingOption) *SkipListHashRing {
+	s := SkipListHashRing{
+		nodeNum:   make(map[string]int64),
+		nodeState: make(map[string]csHash.NodeState),
+		snapshots: make(map[int64]*csHash.RingSnapshot),
+		level:     1,
+		watchers:  make(map[*watcher]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+
+	repairSkipListHashRing(&s.opts)
+	s.head = newVirtualNode(0, s.opts.maxLevel)
+	return &s
+}
+
+// 加锁，锁被占用时轮询等待，直到锁被释放、锁过期或ctx结束
+// expireSecond <= 0 时锁永不过期
+func (s *SkipListHashRing) Lock(ctx context.Context, expireSecond int64) error {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.tryLock(expireSecond) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SkipListHashRing) tryLock(expireSecond int64) bool {
+	s.lockMu.Lock()
+	defer s.lockMu.Unlock()
+
+	now := time.Now()
+	//锁被占用且未过期
+	if s.locked && (s.lockExpireAt.IsZero() || now.Before(s.lockExpireAt)) {
+		return false
+	}
+	s.locked = true
+	s.lockExpireAt = time.Time{}
+	if expireSecond > 0 {
+		s.lockExpireAt = now.Add(time.Duration(expireSecond) * time.Second)
+	}
+	return true
+}
+
+func (s *SkipListHashRing) Unlock(ctx context.Context) error {
+	s.lockMu.Lock()
+	defer s.lockMu.Unlock()
+
+	//锁已经过期，可能已被其他人持有
+	if !s.locked || (!s.lockExpireAt.IsZero() && time.Now().After(s.lockExpireAt)) {
+		return ErrLockNotHeld
+	}
+	s.locked = false
+	return nil
+}
+
+func (s *SkipListHashRing) AddVirtualNode(ctx context.Context, score int64, nodeID string) (version int64, err error) {
+	var change *csHash.RingChange
+	//先解锁再发布修改通知
+	defer func() { s.publish(change) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//如果数据已经存在，直接返回
+	if node := s.search(score); node != nil {
+		for _, virtualNode := range node.nodeID {
+			if virtualNode.VirtualNodeID == nodeID {
+				return virtualNode.Version, nil
+			}
+		}
+	}
+
+	s.version++
+	s.addVirtualNode(score, nodeID, s.version)
+	change = s.newChange(csHash.RingChangeAdd, []csHash.VirtualNodeEntry{{Score: score, VirtualNodeID: nodeID}})
+	return s.version, nil
+}
+
+func (s *SkipListHashRing) RemoveVirtualNode(ctx context.Context, score int64, nodeID string) error {
+	var change *csHash.RingChange
+	defer func() { s.publish(change) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.removeVirtualNode(score, nodeID) {
+		s.version++
+		change = s.newChange(csHash.RingChangeRemove, []csHash.VirtualNodeEntry{{Score: score, VirtualNodeID: nodeID}})
+	}
+	return nil
 }
 
-func (s *skipListHashRing) Lock(ctx context.Context, expireSecond int64) error {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) AddVirtualNodes(ctx context.Context, virtualNodes []csHash.VirtualNodeEntry) (version int64, err error) {
+	var change *csHash.RingChange
+	defer func() { s.publish(change) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := make([]csHash.VirtualNodeEntry, 0, len(virtualNodes))
+	for _, virtualNode := range virtualNodes {
+		if s.addVirtualNode(virtualNode.Score, virtualNode.VirtualNodeID, s.version+1) {
+			changed = append(changed, virtualNode)
+		}
+	}
+	if len(changed) > 0 {
+		s.version++
+		change = s.newChange(csHash.RingChangeAdd, changed)
+	}
+	return s.version, nil
+}
+
+func (s *SkipListHashRing) RemoveVirtualNodes(ctx context.Context, virtualNodes []csHash.VirtualNodeEntry) (version int64, err error) {
+	var change *csHash.RingChange
+	defer func() { s.publish(change) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := make([]csHash.VirtualNodeEntry, 0, len(virtualNodes))
+	for _, virtualNode := range virtualNodes {
+		if s.removeVirtualNode(virtualNode.Score, virtualNode.VirtualNodeID) {
+			changed = append(changed, virtualNode)
+		}
+	}
+	if len(changed) > 0 {
+		s.version++
+		change = s.newChange(csHash.RingChangeRemove, changed)
+	}
+	return s.version, nil
+}
+
+// 订阅哈希环修改通知，ctx结束后关闭channel
+func (s *SkipListHashRing) Watch(ctx context.Context) (<-chan *csHash.RingChange, error) {
+	w := newWatcher()
+	s.watchMu.Lock()
+	s.watchers[w] = struct{}{}
+	s.watchMu.Unlock()
+
+	changes := make(chan *csHash.RingChange)
+	go func() {
+		defer close(changes)
+		defer func() {
+			s.watchMu.Lock()
+			delete(s.watchers, w)
+			s.watchMu.Unlock()
+		}()
+		w.run(ctx, changes)
+	}()
+	return changes, nil
+}
+
+// 生成修改通知并记入当前逻辑操作，调用方需持有s.mu
+func (s *SkipListHashRing) newChange(changeType csHash.RingChangeType, virtualNodes []csHash.VirtualNodeEntry) *csHash.RingChange {
+	return s.recordChange(&csHash.RingChange{
+		Version:      s.version,
+		Type:         changeType,
+		VirtualNodes: virtualNodes,
+	})
+}
+
+// 记入当前逻辑操作，超过MaxOperationChanges时丢弃最早的记录，调用方需持有s.mu
+func (s *SkipListHashRing) recordChange(change *csHash.RingChange) *csHash.RingChange {
+	if len(s.changes) >= csHash.MaxOperationChanges {
+		s.changes = append(s.changes[:0], s.changes[len(s.changes)-csHash.MaxOperationChanges+1:]...)
+	}
+	s.changes = append(s.changes, change)
+	return change
+}
+
+// 将修改通知投递给所有订阅者，不会阻塞修改流程
+func (s *SkipListHashRing) publish(change *csHash.RingChange) {
+	if change == nil {
+		return
+	}
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for w := range s.watchers {
+		w.push(change)
+	}
+}
+
+func (s *SkipListHashRing) GetVirtualNode(ctx context.Context, score int64) (nodeIDs *csHash.HashScore, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	node := s.search(score)
+	if node == nil {
+		return nil, csHash.ErrVirtualNodeNotExists
+	}
+	return toHashScore(node), nil
+}
+
+func (s *SkipListHashRing) GetAllVirtualNodes(ctx context.Context) (hashScores []*csHash.HashScore, version int64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hashScores = make([]*csHash.HashScore, 0)
+	for node := s.first(); node != nil; node = node.next[0] {
+		hashScores = append(hashScores, toHashScore(node))
+	}
+	return hashScores, s.version, nil
+}
+
+func (s *SkipListHashRing) FindDataToVirtualNode(ctx context.Context, dataScore int64) (virtualNodeID string, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	node := s.ceiling(dataScore)
+	//顺时针到达环尾，从环首继续查找
+	if node == nil {
+		node = s.first()
+	}
+	if node == nil {
+		return "", csHash.ErrVirtualNodeNotExists
+	}
+	return node.nodeID[0].VirtualNodeID, nil
+}
+
+func (s *SkipListHashRing) RangeVirtualNodes(ctx context.Context, startScore int64, limit int64) (hashScores []*csHash.HashScore, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hashScores = make([]*csHash.HashScore, 0)
+	for node := s.ceiling(startScore); node != nil && int64(len(hashScores)) < limit; node = node.next[0] {
+		hashScores = append(hashScores, toHashScore(node))
+	}
+	return hashScores, nil
 }
 
-func (s *skipListHashRing) Unlock(ctx context.Context) error {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) AddRealNode(ctx context.Context, nodeName string, replicas int64) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodeNum[nodeName] = replicas
+	if _, ok := s.nodeState[nodeName]; !ok {
+		s.nodeState[nodeName] = csHash.NodeStateActive
+	}
+	return nil
 }
 
-func (s *skipListHashRing) AddVirtualNode(ctx context.Context, score int64, nodeID string) (version int64, err error) {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) GetRealNodes(ctx context.Context) (nodes map[string]int64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	nodes = make(map[string]int64, len(s.nodeNum))
+	for nodeName, replicas := range s.nodeNum {
+		nodes[nodeName] = replicas
+	}
+	return nodes, nil
 }
 
-func (s *skipListHashRing) RemoveVirtualNode(ctx context.Context, score int64, nodeID string) error {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) GetRealNode(ctx context.Context, nodeName string) (replicas int64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nodeNum[nodeName], nil
 }
 
-func (s *skipListHashRing) GetVirtualNode(ctx context.Context, score int64) (nodeIDs *csHash.HashScore, err error) {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) RemoveRealNode(ctx context.Context, nodeName string) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.nodeNum, nodeName)
+	delete(s.nodeState, nodeName)
+	return nil
 }
 
-func (s *skipListHashRing) FindDataToVirtualNode(ctx context.Context, dataScore int64) (virtualNodeID string, err error) {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) GetRealNodeInfos(ctx context.Context) (nodes map[string]csHash.RealNode, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	nodes = make(map[string]csHash.RealNode, len(s.nodeNum))
+	for nodeName, replicas := range s.nodeNum {
+		nodes[nodeName] = csHash.RealNode{
+			Replicas: replicas,
+			State:    s.nodeState[nodeName],
+		}
+	}
+	return nodes, nil
+}
+
+func (s *SkipListHashRing) SetRealNodeState(ctx context.Context, nodeName string, state csHash.NodeState) (err error) {
+	var change *csHash.RingChange
+	defer func() { s.publish(change) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.nodeNum[nodeName]; !ok {
+		return csHash.ErrNodeNotExists
+	}
+	s.nodeState[nodeName] = state
+	s.version++
+	change = s.recordChange(&csHash.RingChange{
+		Version:  s.version,
+		Type:     csHash.RingChangeState,
+		NodeName: nodeName,
+		State:    state,
+	})
+	return nil
 }
 
-func (s *skipListHashRing) AddRealNode(ctx context.Context, nodeName string, replicas int64) (err error) {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) BeginOperation(ctx context.Context) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operationStart = s.version
+	s.changes = nil
+	return nil
 }
 
-func (s *skipListHashRing) GetRealNodes(ctx context.Context) (nodes map[string]int64, err error) {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) GetLastOperation(ctx context.Context) (operation *csHash.RingOperation, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &csHash.RingOperation{
+		StartVersion: s.operationStart,
+		Changes:      append([]*csHash.RingChange(nil), s.changes...),
+	}, nil
 }
 
-func (s *skipListHashRing) GetRealNode(ctx context.Context, nodeName string) (replicas int64, err error) {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) GetVersion(ctx context.Context) (version int64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version, nil
 }
 
-func (s *skipListHashRing) RemoveRealNode(ctx context.Context, nodeName string) (err error) {
-	//TODO implement me
-	panic("implement me")
+func (s *SkipListHashRing) SetVersion(ctx context.Context, version int64) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+	return nil
 }
 
-func (s *skipListHashRing) GetVersion(ctx context.Context) (version int64, err error) {
-	//TODO implement me
-	panic("implement me")
+// 虚拟节点总数
+func (s *SkipListHashRing) VirtualNodeNum() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.virtualNodeNum
 }
 
-func (s *skipListHashRing) SetVersion(ctx context.Context, version int64) (err error) {
-	//TODO implement me
-	panic("implement me")
+func toHashScore(node *virtualNode) *csHash.HashScore {
+	virtualNodes := make([]csHash.VirtualNode, len(node.nodeID))
+	copy(virtualNodes, node.nodeID)
+	return &csHash.HashScore{
+		Score:        node.score,
+		VirtualNodes: virtualNodes,
+	}
 }
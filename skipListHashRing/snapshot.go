@@ -0,0 +1,109 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 19:05:31
+ */
+
+package skipHashRing
+
+import (
+	"context"
+	"sort"
+
+	csHash "github.com/YShiJia/consistentHash"
+)
+
+func (s *SkipListHashRing) SaveSnapshot(ctx context.Context, snapshot *csHash.RingSnapshot) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	//快照不可变，同一版本只保存一次
+	if _, ok := s.snapshots[snapshot.Version]; ok {
+		return nil
+	}
+	s.snapshots[snapshot.Version] = cloneSnapshot(snapshot)
+	return nil
+}
+
+func (s *SkipListHashRing) GetSnapshot(ctx context.Context, version int64) (snapshot *csHash.RingSnapshot, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok := s.snapshots[version]
+	if !ok {
+		return nil, csHash.ErrSnapshotNotExists
+	}
+	return cloneSnapshot(snapshot), nil
+}
+
+func (s *SkipListHashRing) GetSnapshotVersions(ctx context.Context) (versions []int64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions = make([]int64, 0, len(s.snapshots))
+	for version := range s.snapshots {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] < versions[j]
+	})
+	return versions, nil
+}
+
+func (s *SkipListHashRing) DeleteSnapshot(ctx context.Context, version int64) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snapshots, version)
+	return nil
+}
+
+func (s *SkipListHashRing) ResetRing(ctx context.Context, snapshot *csHash.RingSnapshot) (version int64, err error) {
+	var change *csHash.RingChange
+	defer func() { s.publish(change) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//重建跳表，虚拟节点保留快照中的顺序和版本号
+	s.head = newVirtualNode(0, s.opts.maxLevel)
+	s.level = 1
+	s.virtualNodeNum = 0
+	for _, hashScore := range snapshot.HashScores {
+		for _, virtualNode := range hashScore.VirtualNodes {
+			s.addVirtualNode(hashScore.Score, virtualNode.VirtualNodeID, virtualNode.Version)
+		}
+	}
+
+	s.nodeNum = make(map[string]int64, len(snapshot.RealNodes))
+	s.nodeState = make(map[string]csHash.NodeState, len(snapshot.RealNodes))
+	for nodeName, node := range snapshot.RealNodes {
+		s.nodeNum[nodeName] = node.Replicas
+		s.nodeState[nodeName] = node.State
+		if node.State == "" {
+			s.nodeState[nodeName] = csHash.NodeStateActive
+		}
+	}
+
+	s.version++
+	change = s.newChange(csHash.RingChangeReset, nil)
+	return s.version, nil
+}
+
+// 深拷贝快照，避免调用方修改保存的数据
+func cloneSnapshot(snapshot *csHash.RingSnapshot) *csHash.RingSnapshot {
+	clone := csHash.RingSnapshot{
+		Version:    snapshot.Version,
+		HashBits:   snapshot.HashBits,
+		Ketama:     snapshot.Ketama,
+		RealNodes:  make(map[string]csHash.RealNode, len(snapshot.RealNodes)),
+		HashScores: make([]*csHash.HashScore, 0, len(snapshot.HashScores)),
+	}
+	for nodeName, node := range snapshot.RealNodes {
+		clone.RealNodes[nodeName] = node
+	}
+	for _, hashScore := range snapshot.HashScores {
+		virtualNodes := make([]csHash.VirtualNode, len(hashScore.VirtualNodes))
+		copy(virtualNodes, hashScore.VirtualNodes)
+		clone.HashScores = append(clone.HashScores, &csHash.HashScore{
+			Score:        hashScore.Score,
+			VirtualNodes: virtualNodes,
+		})
+	}
+	return &clone
+}
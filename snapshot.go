@@ -0,0 +1,326 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 18:40:16
+ */
+
+package csHash
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// 某个版本哈希环的完整快照，也是Export/Import的数据格式
+type RingSnapshot struct {
+	Version int64 `json:"version" yaml:"version"`
+	// 哈希值位数，HashBits32或HashBits64，为0时按HashBits32处理（兼容旧的快照）
+	HashBits int `json:"hash_bits,omitempty" yaml:"hash_bits,omitempty"`
+	// ketama模式的哈希环，score取值为 [0, 2^32 - 1]
+	Ketama bool `json:"ketama,omitempty" yaml:"ketama,omitempty"`
+	// 真实节点信息
+	RealNodes map[string]RealNode `json:"real_nodes" yaml:"real_nodes"`
+	// 按score升序排列的虚拟节点
+	HashScores []*HashScore `json:"hash_scores" yaml:"hash_scores"`
+}
+
+// 真实节点在哈希环上的分布
+type NodeDistribution struct {
+	Name string `json:"name"`
+	// 虚拟节点个数
+	VirtualNodes int64 `json:"virtual_nodes"`
+	// 负责的哈希值域占比
+	Share float64 `json:"share"`
+}
+
+// 计算每个真实节点的虚拟节点个数和负责的哈希值域占比，按节点名排序
+// (前一个score, score]区间属于score上的首个虚拟节点
+func (s *RingSnapshot) Distribution() []NodeDistribution {
+	byName := make(map[string]*NodeDistribution)
+	get := func(nodeName string) *NodeDistribution {
+		if byName[nodeName] == nil {
+			byName[nodeName] = &NodeDistribution{Name: nodeName}
+		}
+		return byName[nodeName]
+	}
+	for nodeName := range s.RealNodes {
+		get(nodeName)
+	}
+
+	view := newRingView(s.Version, s.HashScores, nil)
+	for i, hashScore := range view.hashScores {
+		for _, virtualNode := range hashScore.VirtualNodes {
+			if nodeName, _, err := parseVirtualNodeID(virtualNode.VirtualNodeID); err == nil {
+				get(nodeName).VirtualNodes++
+			}
+		}
+		nodeName, _, err := parseVirtualNodeID(hashScore.VirtualNodes[0].VirtualNodeID)
+		if err != nil {
+			continue
+		}
+		prevScore := view.hashScores[(i+len(view.hashScores)-1)%len(view.hashScores)].Score
+		get(nodeName).Share += s.arcShare(prevScore, hashScore.Score)
+	}
+
+	distribution := make([]NodeDistribution, 0, len(byName))
+	for _, node := range byName {
+		distribution = append(distribution, *node)
+	}
+	sort.Slice(distribution, func(i, j int) bool {
+		return distribution[i].Name < distribution[j].Name
+	})
+	return distribution
+}
+
+// 区间(prevScore, score]占整个哈希值域的比例，prevScore等于score时表示整个哈希环
+func (s *RingSnapshot) arcShare(prevScore, score int64) float64 {
+	if s.Ketama {
+		length := uint32(score - prevScore)
+		if length == 0 {
+			return 1
+		}
+		return float64(length) / (1 << 32)
+	}
+	if s.hashBits() == HashBits64 {
+		//int64相减溢出后按uint64解释，正好是顺时针距离
+		length := uint64(score - prevScore)
+		if length == 0 {
+			return 1
+		}
+		return float64(length) / (1 << 64)
+	}
+	length := score - prevScore
+	if length <= 0 {
+		length += hashSpace
+	}
+	return float64(length) / hashSpace
+}
+
+func (s *RingSnapshot) hashBits() int {
+	if s.HashBits == 0 {
+		return HashBits32
+	}
+	return s.HashBits
+}
+
+// 支持按版本号保存哈希环快照的哈希环
+type SnapshotHashRing interface {
+	HashRing
+
+	// 保存快照，同一版本号的快照只保存一次，已存在时直接返回
+	SaveSnapshot(ctx context.Context, snapshot *RingSnapshot) (err error)
+	// 获取指定版本的快照，不存在时返回ErrSnapshotNotExists
+	GetSnapshot(ctx context.Context, version int64) (snapshot *RingSnapshot, err error)
+	// 获取所有已保存快照的版本号，按升序排列
+	GetSnapshotVersions(ctx context.Context) (versions []int64, err error)
+	// 删除指定版本的快照，不存在直接返回
+	DeleteSnapshot(ctx context.Context, version int64) (err error)
+	// 用快照中的虚拟节点和真实节点整体替换当前哈希环，版本号加一并发布RingChangeReset修改通知，返回替换后的版本号
+	ResetRing(ctx context.Context, snapshot *RingSnapshot) (version int64, err error)
+}
+
+// 开启快照并且哈希环支持快照时返回哈希环，否则返回nil
+func (c *ConsistentHash) snapshotRing() SnapshotHashRing {
+	if !c.opts.snapshot {
+		return nil
+	}
+	snapshotRing, _ := c.hashRing.(SnapshotHashRing)
+	return snapshotRing
+}
+
+// 为当前版本的哈希环保存快照，当前版本已有快照时直接返回，调用方需持有哈希环锁
+func (c *ConsistentHash) saveSnapshot(ctx context.Context) error {
+	snapshotRing := c.snapshotRing()
+	if snapshotRing == nil {
+		return nil
+	}
+	version, err := snapshotRing.GetVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := snapshotRing.GetSnapshot(ctx, version); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrSnapshotNotExists) {
+		return err
+	}
+
+	hashScores, version, err := c.getAllVirtualNodes(ctx)
+	if err != nil {
+		return err
+	}
+	nodes, err := readRealNodeInfos(ctx, c.hashRing)
+	if err != nil {
+		return err
+	}
+	snapshot := RingSnapshot{
+		Version:    version,
+		HashBits:   c.hashBits(),
+		Ketama:     c.ketama,
+		RealNodes:  nodes,
+		HashScores: hashScores,
+	}
+	if err := snapshotRing.SaveSnapshot(ctx, &snapshot); err != nil {
+		return err
+	}
+	return c.trimSnapshots(ctx, snapshotRing)
+}
+
+// 修改哈希环之后保存快照，修改本身已经成功，保存失败只记录日志
+func (c *ConsistentHash) saveSnapshotAfterChange(ctx context.Context) {
+	if err := c.saveSnapshot(ctx); err != nil && c.logger != nil {
+		c.logger.Warn("save ring snapshot failed", "err", err)
+	}
+}
+
+// 快照数量超过上限时删除最旧的快照
+func (c *ConsistentHash) trimSnapshots(ctx context.Context, snapshotRing SnapshotHashRing) error {
+	if c.opts.snapshotLimit <= 0 {
+		return nil
+	}
+	versions, err := snapshotRing.GetSnapshotVersions(ctx)
+	if err != nil {
+		return err
+	}
+	for i := 0; int64(len(versions)-i) > c.opts.snapshotLimit; i++ {
+		if err := snapshotRing.DeleteSnapshot(ctx, versions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取指定版本的哈希环快照
+func (c *ConsistentHash) GetSnapshot(ctx context.Context, version int64) (*RingSnapshot, error) {
+	snapshotRing := c.snapshotRing()
+	if snapshotRing == nil {
+		return nil, ErrSnapshotNotSupported
+	}
+	return snapshotRing.GetSnapshot(ctx, version)
+}
+
+// 获取所有已保存快照的版本号，按升序排列
+func (c *ConsistentHash) GetSnapshotVersions(ctx context.Context) ([]int64, error) {
+	snapshotRing := c.snapshotRing()
+	if snapshotRing == nil {
+		return nil, ErrSnapshotNotSupported
+	}
+	return snapshotRing.GetSnapshotVersions(ctx)
+}
+
+// 按指定版本的哈希环查找数据所属的节点，用于审计历史路由
+// 与GetNode一致，跳过该版本中不可用的节点，不跳过下线中的节点，不考虑健康检查和负载
+func (c *ConsistentHash) GetNodeAt(ctx context.Context, dataKey string, version int64) (string, error) {
+	snapshot, err := c.GetSnapshot(ctx, version)
+	if err != nil {
+		return "", err
+	}
+	view := newRingView(snapshot.Version, snapshot.HashScores, snapshot.RealNodes)
+	return view.lookup(c.score(dataKey), false)
+}
+
+// 将哈希环回滚到指定版本的快照，回滚本身是一次新的修改，版本号继续递增
+// 回滚前后归属发生变化的区间交给迁移回调
+func (c *ConsistentHash) Rollback(ctx context.Context, version int64) error {
+	snapshotRing := c.snapshotRing()
+	if snapshotRing == nil {
+		return ErrSnapshotNotSupported
+	}
+	if err := c.hashRing.Lock(ctx, c.opts.lockExpireSeconds); err != nil {
+		return err
+	}
+
+	defer c.hashRing.Unlock(ctx)
+
+	target, err := snapshotRing.GetSnapshot(ctx, version)
+	if err != nil {
+		return err
+	}
+	// 先保存回滚前的快照，回滚本身也可以被撤销
+	if err := c.saveSnapshot(ctx); err != nil {
+		return err
+	}
+	current, _, err := c.getAllVirtualNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.beginOperation(ctx); err != nil {
+		return err
+	}
+	if _, err := snapshotRing.ResetRing(ctx, target); err != nil {
+		return err
+	}
+	c.saveSnapshotAfterChange(ctx)
+	c.refreshCacheAfterChange(ctx)
+
+	arcs, err := diffMigrationArcs(current, target.HashScores)
+	if err != nil {
+		return err
+	}
+	return c.migrate(ctx, arcs)
+}
+
+// 对比两个版本的哈希环，计算归属发生变化的区间
+// 两个哈希环上所有score把哈希环切分成若干区间，每个区间在两个版本中分别属于各自顺时针的第一个虚拟节点
+func diffMigrationArcs(from, to []*HashScore) ([]migrationArc, error) {
+	return diffMigrationArcsWithNodes(from, to, nil)
+}
+
+// 与diffMigrationArcs相同，nodes不为nil时新版本中的归属按读取的方式跳过不可用的节点，没有可读节点时仍取第一个虚拟节点
+func diffMigrationArcsWithNodes(from, to []*HashScore, nodes map[string]RealNode) ([]migrationArc, error) {
+	fromView := newRingView(0, from, nil)
+	toView := newRingView(0, to, nodes)
+	if len(fromView.hashScores) == 0 || len(toView.hashScores) == 0 {
+		return nil, nil
+	}
+
+	scoreSet := make(map[int64]struct{})
+	for _, hashScore := range fromView.hashScores {
+		scoreSet[hashScore.Score] = struct{}{}
+	}
+	for _, hashScore := range toView.hashScores {
+		scoreSet[hashScore.Score] = struct{}{}
+	}
+	scores := make([]int64, 0, len(scoreSet))
+	for score := range scoreSet {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i] < scores[j]
+	})
+
+	arcs := make([]migrationArc, 0)
+	for i, score := range scores {
+		fromID, err := fromView.findDataToVirtualNode(score)
+		if err != nil {
+			return nil, err
+		}
+		toID, err := toView.findDataToVirtualNode(score)
+		if err != nil {
+			return nil, err
+		}
+		fromNode, _, err := parseVirtualNodeID(fromID)
+		if err != nil {
+			return nil, err
+		}
+		toNode, _, err := parseVirtualNodeID(toID)
+		if err != nil {
+			return nil, err
+		}
+		if nodes != nil {
+			if readableNode, err := toView.lookup(score, false); err == nil {
+				toNode = readableNode
+			} else if !errors.Is(err, ErrNoAvailableNode) {
+				return nil, err
+			}
+		}
+		if fromNode == toNode {
+			continue
+		}
+		// 第一个区间的起点为最后一个score，跨越哈希环首尾
+		start := scores[(i+len(scores)-1)%len(scores)]
+		arcs = append(arcs, migrationArc{start: start, score: score, from: fromNode, to: toNode})
+	}
+	return arcs, nil
+}
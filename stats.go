@@ -0,0 +1,95 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 10:12:36
+ */
+
+package csHash
+
+import (
+	"context"
+	"math"
+)
+
+// 单个真实节点的分布情况
+type NodeStats struct {
+	NodeDistribution
+	Replicas int64     `json:"replicas"`
+	State    NodeState `json:"state"`
+	// 按映射数量计算的期望占比
+	ExpectedShare float64 `json:"expected_share"`
+	// 实际占比与期望占比之比，1表示完全均衡
+	Load float64 `json:"load"`
+}
+
+// 哈希环的分布统计，用于调整映射数量和权重
+type RingStats struct {
+	Version      int64       `json:"version"`
+	VirtualNodes int64       `json:"virtual_nodes"`
+	Nodes        []NodeStats `json:"nodes"`
+	// 各节点Load的标准差，越接近0越均衡
+	StdDev float64 `json:"std_dev"`
+	// 最大Load与最小Load之比，越接近1越均衡，最小Load为0时无法计算，为0
+	MaxMinRatio float64 `json:"max_min_ratio"`
+}
+
+// 统计每个真实节点负责的哈希值域占比（相邻score之间的区间长度之和，32位和64位哈希按各自的值域计算）、虚拟节点个数，以及整体的标准差和最大最小比
+// 标准差和最大最小比按Load计算，节点权重不同时同样适用；没有映射的节点不参与计算
+func (c *ConsistentHash) Stats(ctx context.Context) (RingStats, error) {
+	snapshot, err := c.Export(ctx)
+	if err != nil {
+		return RingStats{}, err
+	}
+
+	var totalReplicas int64
+	for _, node := range snapshot.RealNodes {
+		totalReplicas += max(node.Replicas, 0)
+	}
+
+	stats := RingStats{Version: snapshot.Version}
+	loads := make([]float64, 0, len(snapshot.RealNodes))
+	for _, distribution := range snapshot.Distribution() {
+		node := snapshot.RealNodes[distribution.Name]
+		nodeStats := NodeStats{
+			NodeDistribution: distribution,
+			Replicas:         node.Replicas,
+			State:            node.State,
+		}
+		stats.VirtualNodes += distribution.VirtualNodes
+		if totalReplicas > 0 && node.Replicas > 0 {
+			nodeStats.ExpectedShare = float64(node.Replicas) / float64(totalReplicas)
+			nodeStats.Load = nodeStats.Share / nodeStats.ExpectedShare
+			loads = append(loads, nodeStats.Load)
+		}
+		stats.Nodes = append(stats.Nodes, nodeStats)
+	}
+	if stats.Nodes == nil {
+		stats.Nodes = []NodeStats{}
+	}
+	stats.StdDev, stats.MaxMinRatio = balance(loads)
+	return stats, nil
+}
+
+// 计算标准差和最大最小比，最小值为0时最大最小比为0，没有数据时均为0
+func balance(values []float64) (stdDev, maxMinRatio float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	minValue, maxValue := values[0], values[0]
+	for _, value := range values {
+		sum += value
+		minValue = min(minValue, value)
+		maxValue = max(maxValue, value)
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	stdDev = math.Sqrt(variance / float64(len(values)))
+	if minValue == 0 {
+		return stdDev, 0
+	}
+	return stdDev, maxValue / minValue
+}
@@ -0,0 +1,1316 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 13:48:26
+ */
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/redisHashRing"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConsistentHashLocalCache(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	admin := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	cached := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithLocalCache(0))
+	defer cached.Close()
+
+	if err := admin.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if nodeName, err := cached.GetNode(ctx, "data"); err != nil || nodeName != "node_a" {
+		t.Fatalf("expect node_a, got %s, err: %v", nodeName, err)
+	}
+
+	if err := admin.AddNode(ctx, "node_b", 10); err != nil {
+		t.Fatal(err)
+	}
+	//刷新之前使用的仍然是旧版本的本地副本
+	for i := 0; i < 100; i++ {
+		if nodeName, _ := cached.GetNode(ctx, fmt.Sprintf("data_%d", i)); nodeName != "node_a" {
+			t.Fatalf("stale cache, expect node_a, got %s", nodeName)
+		}
+	}
+	if err := cached.RefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertSameRouting(t, admin, cached)
+}
+
+func TestConsistentHashLocalCachePolling(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	admin := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	cached := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithLocalCache(10*time.Millisecond))
+	defer cached.Close()
+
+	if err := admin.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.GetNode(ctx, "data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.AddNode(ctx, "node_b", 3); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assertSameRouting(t, admin, cached)
+}
+
+// 两个ConsistentHash对同一批数据的路由结果应该一致
+func assertSameRouting(t *testing.T, expect, actual *csHash.ConsistentHash) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < 200; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		expectNode, err := expect.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actualNode, err := actual.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectNode != actualNode {
+			t.Fatalf("data key %s, expect %s, got %s", dataKey, expectNode, actualNode)
+		}
+	}
+}
+
+func TestConsistentHashWatch(t *testing.T) {
+	testConsistentHashWatch(t, skipHashRing.NewSkipListHashRing())
+}
+
+// 添加、删除节点之后，订阅方按顺序收到真实节点维度的事件
+func testConsistentHashWatch(t *testing.T, ring csHash.HashRing) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	events, err := ch.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.AddNode(ctx, "node_b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.RemoveNode(ctx, "node_a"); err != nil {
+		t.Fatal(err)
+	}
+
+	expects := []csHash.RingEvent{
+		{Version: 1, Type: csHash.RingEventNodeAdded, NodeName: "node_a"},
+		{Version: 2, Type: csHash.RingEventNodeAdded, NodeName: "node_b"},
+		{Version: 3, Type: csHash.RingEventNodeRemoved, NodeName: "node_a"},
+	}
+	for _, expect := range expects {
+		select {
+		case event := <-events:
+			if event != expect {
+				t.Fatalf("expect %+v, got %+v", expect, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("wait for %+v timeout", expect)
+		}
+	}
+
+	cancel()
+	//ctx结束后channel关闭
+	for range events {
+	}
+}
+
+func TestConsistentHashGetNodes(t *testing.T) {
+	testConsistentHashGetNodes(t, skipHashRing.NewSkipListHashRing())
+}
+
+// GetNodes的首个节点与GetNode一致，节点互不相同，开启本地缓存前后结果一致
+func testConsistentHashGetNodes(t *testing.T, ring csHash.HashRing) {
+	ctx := context.Background()
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	cached := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithLocalCache(0))
+	defer cached.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, err := ch.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodeNames, err := ch.GetNodes(ctx, dataKey, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodeNames) != 3 || nodeNames[0] != nodeName {
+			t.Fatalf("data key %s, GetNode: %s, GetNodes: %v", dataKey, nodeName, nodeNames)
+		}
+		if nodeNames[0] == nodeNames[1] || nodeNames[1] == nodeNames[2] || nodeNames[0] == nodeNames[2] {
+			t.Fatalf("duplicate nodes: %v", nodeNames)
+		}
+		cachedNodeNames, err := cached.GetNodes(ctx, dataKey, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(cachedNodeNames) != fmt.Sprint(nodeNames) {
+			t.Fatalf("expect %v, got %v", nodeNames, cachedNodeNames)
+		}
+	}
+
+	//真实节点不足时返回全部真实节点
+	nodeNames, err := ch.GetNodes(ctx, "data", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeNames) != 5 {
+		t.Fatalf("expect 5 nodes, got %v", nodeNames)
+	}
+	//n远大于真实节点个数时，不按n分配内存
+	nodeNames, err = ch.GetNodes(ctx, "data", 1<<40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeNames) != 5 {
+		t.Fatalf("expect 5 nodes, got %v", nodeNames)
+	}
+}
+
+func TestConsistentHashBoundedLoad(t *testing.T) {
+	ctx := context.Background()
+	ch := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil, csHash.WithBoundedLoad(1.25))
+	for i := 0; i < 4; i++ {
+		if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//同一个热点key反复分配，负载会被分摊到其他节点
+	for i := 0; i < 1000; i++ {
+		nodeName, err := ch.GetNode(ctx, "hot_key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch.Inc(nodeName)
+	}
+	loads := ch.Loads()
+	if len(loads) != 4 {
+		t.Fatalf("expect load on 4 nodes, got %v", loads)
+	}
+	for nodeName, load := range loads {
+		maxLoad, err := ch.MaxLoad(ctx, nodeName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if load > maxLoad || load > 313 {
+			t.Fatalf("node %s overloaded, load: %d, max load: %d", nodeName, load, maxLoad)
+		}
+	}
+
+	for nodeName, load := range loads {
+		for i := int64(0); i < load; i++ {
+			ch.Done(nodeName)
+		}
+	}
+	if loads := ch.Loads(); len(loads) != 0 {
+		t.Fatalf("expect no load, got %v", loads)
+	}
+}
+
+func TestConsistentHashHealthChecker(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	checker := csHash.NewManualHealthChecker()
+	plain := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithHealthChecker(checker))
+	for i := 0; i < 3; i++ {
+		if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version, _ := ring.GetVersion(ctx)
+
+	checker.MarkDown("node_1")
+	for i := 0; i < 100; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, err := ch.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodeName == "node_1" {
+			t.Fatalf("%s routed to unhealthy node", dataKey)
+		}
+		//原本不属于node_1的数据不受影响
+		if owner, _ := plain.GetNode(ctx, dataKey); owner != "node_1" && owner != nodeName {
+			t.Fatalf("%s moved from %s to %s", dataKey, owner, nodeName)
+		}
+		nodeNames, err := ch.GetNodes(ctx, dataKey, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodeNames) != 2 {
+			t.Fatalf("expect 2 healthy nodes, got %v", nodeNames)
+		}
+	}
+	//健康检查不修改哈希环
+	if v, _ := ring.GetVersion(ctx); v != version {
+		t.Fatalf("ring changed, version %d -> %d", version, v)
+	}
+
+	checker.MarkDown("node_0")
+	checker.MarkDown("node_2")
+	if _, err := ch.GetNode(ctx, "data"); !errors.Is(err, csHash.ErrNoAvailableNode) {
+		t.Fatalf("expect ErrNoAvailableNode, got %v", err)
+	}
+
+	checker.MarkUp("node_0")
+	checker.MarkUp("node_1")
+	checker.MarkUp("node_2")
+	for i := 0; i < 100; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		owner, _ := plain.GetNode(ctx, dataKey)
+		if nodeName, _ := ch.GetNode(ctx, dataKey); nodeName != owner {
+			t.Fatalf("%s expect %s after recovery, got %s", dataKey, owner, nodeName)
+		}
+	}
+}
+
+func TestProbeHealthChecker(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	checker := csHash.NewProbeHealthChecker(csHash.TCPProber(),
+		csHash.WithProbeInterval(10*time.Millisecond), csHash.WithFailureThreshold(1))
+	defer checker.Close()
+
+	//第一次遇到的节点在探测之前视为健康
+	if !checker.IsHealthy(ctx, addr) {
+		t.Fatal("new node should be healthy")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !checker.IsHealthy(ctx, addr) {
+		t.Fatal("listening node should be healthy")
+	}
+
+	listener.Close()
+	deadline := time.Now().Add(time.Second)
+	for checker.IsHealthy(ctx, addr) {
+		if time.Now().After(deadline) {
+			t.Fatal("closed node should be unhealthy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConsistentHashUpdateNodeWeight(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithReplicas(5))
+	if err := ch.UpdateNodeWeight(ctx, "node_a", 2); !errors.Is(err, csHash.ErrNodeNotExists) {
+		t.Fatalf("expect ErrNodeNotExists, got %v", err)
+	}
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ch.UpdateNodeWeight(ctx, "node_a", 3); err != nil {
+		t.Fatal(err)
+	}
+	if replicas, _ := ring.GetRealNode(ctx, "node_a"); replicas != 15 {
+		t.Fatalf("expect 15 replicas, got %d", replicas)
+	}
+	if num := ring.VirtualNodeNum(); num != 15 {
+		t.Fatalf("expect 15 virtual nodes, got %d", num)
+	}
+	//添加节点、增加权重各修改一次哈希环
+	if version, _ := ring.GetVersion(ctx); version != 2 {
+		t.Fatalf("expect version 2, got %d", version)
+	}
+
+	if err := ch.UpdateNodeWeight(ctx, "node_a", 2); err != nil {
+		t.Fatal(err)
+	}
+	if num := ring.VirtualNodeNum(); num != 10 {
+		t.Fatalf("expect 10 virtual nodes, got %d", num)
+	}
+	if _, err := ring.GetVirtualNode(ctx, int64(csHash.NewMurmurHasher32().Encrypt("node_a_11"))); !errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Fatalf("node_a_11 should be removed, err: %v", err)
+	}
+}
+
+// 统计单点读取哈希环的次数，用于检查节点变更不会按虚拟节点逐个访问哈希环
+type countingHashRing struct {
+	*skipHashRing.SkipListHashRing
+	pointReads    int
+	fullReads     int
+	nodeInfoReads int
+	rangeReads    int
+}
+
+func (r *countingHashRing) RangeVirtualNodes(ctx context.Context, startScore int64, limit int64) ([]*csHash.HashScore, error) {
+	r.rangeReads++
+	return r.SkipListHashRing.RangeVirtualNodes(ctx, startScore, limit)
+}
+
+func (r *countingHashRing) GetRealNodeInfos(ctx context.Context) (map[string]csHash.RealNode, error) {
+	r.nodeInfoReads++
+	return r.SkipListHashRing.GetRealNodeInfos(ctx)
+}
+
+func (r *countingHashRing) GetVirtualNode(ctx context.Context, score int64) (*csHash.HashScore, error) {
+	r.pointReads++
+	return r.SkipListHashRing.GetVirtualNode(ctx, score)
+}
+
+func (r *countingHashRing) FindDataToVirtualNode(ctx context.Context, dataScore int64) (string, error) {
+	r.pointReads++
+	return r.SkipListHashRing.FindDataToVirtualNode(ctx, dataScore)
+}
+
+func (r *countingHashRing) GetAllVirtualNodes(ctx context.Context) ([]*csHash.HashScore, int64, error) {
+	r.fullReads++
+	return r.SkipListHashRing.GetAllVirtualNodes(ctx)
+}
+
+// 未开启本地缓存时，真实节点信息只在哈希环版本号变化后重新读取
+func TestConsistentHashLookupNodeInfos(t *testing.T) {
+	ctx := context.Background()
+	ring := &countingHashRing{SkipListHashRing: skipHashRing.NewSkipListHashRing()}
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	for _, nodeName := range []string{"node_a", "node_b"} {
+		if err := ch.AddNode(ctx, nodeName, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lookup := func() {
+		ring.nodeInfoReads = 0
+		for i := 0; i < 100; i++ {
+			if _, err := ch.GetNode(ctx, fmt.Sprintf("data_%d", i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if ring.nodeInfoReads != 1 {
+			t.Fatalf("expect 1 node info read, got %d", ring.nodeInfoReads)
+		}
+	}
+	lookup()
+	if err := ch.SetNodeState(ctx, "node_a", csHash.NodeStateDown); err != nil {
+		t.Fatal(err)
+	}
+	lookup()
+	for i := 0; i < 100; i++ {
+		if nodeName, _ := ch.GetNode(ctx, fmt.Sprintf("data_%d", i)); nodeName != "node_b" {
+			t.Fatalf("expect node_b, got %s", nodeName)
+		}
+	}
+}
+
+// 删除节点时先删除真实节点，虚拟节点删除之前查找到的剩余虚拟节点需要跳过
+func TestConsistentHashLookupRemovedRealNode(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	admin := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	for _, nodeName := range []string{"node_a", "node_b"} {
+		if err := admin.AddNode(ctx, nodeName, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ring.RemoveRealNode(ctx, "node_b"); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	cached := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithLocalCache(0))
+	defer cached.Close()
+	for i := 0; i < 100; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		for _, c := range []*csHash.ConsistentHash{ch, cached} {
+			if nodeName, err := c.GetNode(ctx, dataKey); err != nil || nodeName != "node_a" {
+				t.Fatalf("data key %s, expect node_a, got %s, err: %v", dataKey, nodeName, err)
+			}
+			if nodeName, err := c.GetNodeForWrite(ctx, dataKey); err != nil || nodeName != "node_a" {
+				t.Fatalf("data key %s, expect node_a for write, got %s, err: %v", dataKey, nodeName, err)
+			}
+		}
+	}
+}
+
+func TestConsistentHashDrainNode(t *testing.T) {
+	testConsistentHashDrainNode(t, skipHashRing.NewSkipListHashRing(), csHash.NewMemoryDataKeyStore())
+	//不支持批量修改时逐个删除虚拟节点
+	testConsistentHashDrainNode(t, statefulPlainHashRing{skipHashRing.NewSkipListHashRing()}, csHash.NewMemoryDataKeyStore())
+}
+
+// 下线中的节点不再接收新数据，但读取仍然落在它上面，DrainNode迁出数据后删除节点
+func testConsistentHashDrainNode(t *testing.T, ring csHash.StatefulHashRing, store csHash.DataKeyStore) {
+	ctx := context.Background()
+	owners := make(map[string]string)
+	migrations := 0
+	migrator := func(ctx context.Context, dataKeys map[string]struct{}, from, to string) error {
+		migrations++
+		for dataKey := range dataKeys {
+			//下线期间写入的数据已经在后继节点上
+			if _, ok := owners[dataKey]; !ok {
+				continue
+			}
+			if owners[dataKey] != from {
+				return fmt.Errorf("data key %s belongs to %s, not %s", dataKey, owners[dataKey], from)
+			}
+			owners[dataKey] = to
+		}
+		return nil
+	}
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), migrator,
+		csHash.WithDataKeyStore(store), csHash.WithRecordOnGetNode())
+	for _, nodeName := range []string{"node_a", "node_b", "node_c"} {
+		if err := ch.AddNode(ctx, nodeName, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 300; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, err := ch.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[dataKey] = nodeName
+	}
+
+	if err := ch.SetNodeState(ctx, "node_x", csHash.NodeStateDown); !errors.Is(err, csHash.ErrNodeNotExists) {
+		t.Fatalf("expect ErrNodeNotExists, got %v", err)
+	}
+	if err := ch.SetNodeState(ctx, "node_b", csHash.NodeStateDraining); err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := ring.GetRealNodeInfos(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes["node_b"].State != csHash.NodeStateDraining || nodes["node_b"].Replicas != 10 {
+		t.Fatalf("unexpected node_b: %+v", nodes["node_b"])
+	}
+	for i := 0; i < 300; i++ {
+		if nodeName, _ := ch.GetNodeForWrite(ctx, fmt.Sprintf("new_data_%d", i)); nodeName == "node_b" {
+			t.Fatal("draining node should not receive new data")
+		}
+	}
+	//数据迁出之前，读取仍然落在下线中的节点上
+	for dataKey, owner := range owners {
+		if nodeName, _ := ch.GetNode(ctx, dataKey); nodeName != owner {
+			t.Fatalf("data key %s should still be read from %s, got %s", dataKey, owner, nodeName)
+		}
+	}
+
+	version, _ := ring.GetVersion(ctx)
+	if err := ch.DrainNode(ctx, "node_b"); err != nil {
+		t.Fatal(err)
+	}
+	if migrations == 0 {
+		t.Fatal("expect migrations")
+	}
+	//支持批量修改时，修改状态之后一次性删除全部虚拟节点
+	if _, ok := ring.(csHash.BatchHashRing); ok {
+		if latestVersion, _ := ring.GetVersion(ctx); latestVersion != version+2 {
+			t.Fatalf("expect version %d after drain, got %d", version+2, latestVersion)
+		}
+	}
+	nodes, err = ring.GetRealNodeInfos(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nodes["node_b"]; ok || len(nodes) != 2 {
+		t.Fatalf("node_b should be removed, nodes: %v", nodes)
+	}
+	for dataKey, owner := range owners {
+		if nodeName, _ := ch.GetNode(ctx, dataKey); nodeName != owner {
+			t.Fatalf("data key %s, expect %s, got %s", dataKey, owner, nodeName)
+		}
+	}
+
+	if err := ch.SetNodeState(ctx, "node_a", csHash.NodeStateDown); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.SetNodeState(ctx, "node_c", csHash.NodeStateDown); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ch.GetNode(ctx, "data"); !errors.Is(err, csHash.ErrNoAvailableNode) {
+		t.Fatalf("expect ErrNoAvailableNode, got %v", err)
+	}
+}
+
+// 记录修改真实节点和删除虚拟节点的顺序，删除虚拟节点会递增版本号
+type drainOrderHashRing struct {
+	csHash.StatefulHashRing
+	ops *[]string
+}
+
+func (r drainOrderHashRing) AddRealNode(ctx context.Context, nodeName string, replicas int64) error {
+	*r.ops = append(*r.ops, "node")
+	return r.StatefulHashRing.AddRealNode(ctx, nodeName, replicas)
+}
+
+func (r drainOrderHashRing) RemoveRealNode(ctx context.Context, nodeName string) error {
+	*r.ops = append(*r.ops, "node")
+	return r.StatefulHashRing.RemoveRealNode(ctx, nodeName)
+}
+
+func (r drainOrderHashRing) RemoveVirtualNode(ctx context.Context, score int64, virtualNodeID string) error {
+	*r.ops = append(*r.ops, "version")
+	return r.StatefulHashRing.RemoveVirtualNode(ctx, score, virtualNodeID)
+}
+
+// 在drainOrderHashRing的基础上支持批量修改
+type batchDrainOrderHashRing struct {
+	drainOrderHashRing
+	batch csHash.BatchHashRing
+}
+
+func (r batchDrainOrderHashRing) AddVirtualNodes(ctx context.Context, virtualNodes []csHash.VirtualNodeEntry) (int64, error) {
+	return r.batch.AddVirtualNodes(ctx, virtualNodes)
+}
+
+func (r batchDrainOrderHashRing) RemoveVirtualNodes(ctx context.Context, virtualNodes []csHash.VirtualNodeEntry) (int64, error) {
+	*r.ops = append(*r.ops, "version")
+	return r.batch.RemoveVirtualNodes(ctx, virtualNodes)
+}
+
+// 下线的每一步都先修改真实节点再递增版本号，按版本号缓存真实节点信息的进程不会读到旧的映射数量和状态
+func TestConsistentHashDrainNodeVersionOrder(t *testing.T) {
+	ctx := context.Background()
+	ops := make([]string, 0)
+	plain := drainOrderHashRing{StatefulHashRing: skipHashRing.NewSkipListHashRing(), ops: &ops}
+	skipList := skipHashRing.NewSkipListHashRing()
+	batch := batchDrainOrderHashRing{drainOrderHashRing{StatefulHashRing: skipList, ops: &ops}, skipList}
+
+	for _, c := range []struct {
+		ring  csHash.StatefulHashRing
+		steps int
+	}{{plain, 10}, {batch, 1}} {
+		ch := csHash.NewConsistentHash(c.ring, csHash.NewMurmurHasher32(), nil)
+		for _, nodeName := range []string{"node_a", "node_b"} {
+			if err := ch.AddNode(ctx, nodeName, 2); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ops = ops[:0]
+		if err := ch.DrainNode(ctx, "node_b"); err != nil {
+			t.Fatal(err)
+		}
+		expect := make([]string, 0, 2*c.steps)
+		for i := 0; i < c.steps; i++ {
+			expect = append(expect, "node", "version")
+		}
+		if fmt.Sprint(ops) != fmt.Sprint(expect) {
+			t.Fatalf("expect %v, got %v", expect, ops)
+		}
+		if nodes, _ := ch.ListNodes(ctx); len(nodes) != 1 {
+			t.Fatalf("expect node_b removed, got %v", nodes)
+		}
+	}
+}
+
+func TestConsistentHashGetNodeWithHistory(t *testing.T) {
+	testConsistentHashGetNodeWithHistory(t, skipHashRing.NewSkipListHashRing())
+}
+
+func testConsistentHashGetNodeWithHistory(t *testing.T, ring csHash.HashRing) {
+	ctx := context.Background()
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithReplicas(20))
+	getOwners := func() map[string]string {
+		owners := make(map[string]string)
+		for i := 0; i < 200; i++ {
+			dataKey := fmt.Sprintf("data_%d", i)
+			nodeName, err := ch.GetNode(ctx, dataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			owners[dataKey] = nodeName
+		}
+		return owners
+	}
+	assertHistory := func(previous, current map[string]string) {
+		for dataKey := range current {
+			nodeName, previousNodeName, err := ch.GetNodeWithHistory(ctx, dataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nodeName != current[dataKey] || previousNodeName != previous[dataKey] {
+				t.Fatalf("%s expect (%s, %s), got (%s, %s)", dataKey, current[dataKey], previous[dataKey], nodeName, previousNodeName)
+			}
+		}
+	}
+
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	//上一版本哈希环为空
+	assertHistory(map[string]string{}, getOwners())
+
+	if err := ch.AddNode(ctx, "node_b", 1); err != nil {
+		t.Fatal(err)
+	}
+	beforeAdd := getOwners()
+	if err := ch.AddNode(ctx, "node_c", 2); err != nil {
+		t.Fatal(err)
+	}
+	afterAdd := getOwners()
+	assertHistory(beforeAdd, afterAdd)
+
+	if err := ch.RemoveNode(ctx, "node_a"); err != nil {
+		t.Fatal(err)
+	}
+	afterRemove := getOwners()
+	assertHistory(afterAdd, afterRemove)
+
+	//只修改节点状态时，上一版本的归属就是虚拟节点的归属
+	if err := ch.SetNodeState(ctx, "node_b", csHash.NodeStateDraining); err != nil {
+		t.Fatal(err)
+	}
+	afterState := getOwners()
+	for dataKey, nodeName := range afterState {
+		if nodeName != afterRemove[dataKey] {
+			t.Fatalf("%s should still be read from %s, got %s", dataKey, afterRemove[dataKey], nodeName)
+		}
+		if nodeName, _ := ch.GetNodeForWrite(ctx, dataKey); nodeName == "node_b" {
+			t.Fatalf("%s should skip draining node for write", dataKey)
+		}
+	}
+	assertHistory(afterRemove, afterState)
+}
+
+func TestConsistentHashGetNodeWithHistoryDuringDrain(t *testing.T) {
+	testConsistentHashGetNodeWithHistoryDuringDrain(t, skipHashRing.NewSkipListHashRing())
+}
+
+// 下线过程中逐个删除虚拟节点，上一版本始终是下线开始之前的哈希环，而不是上一个虚拟节点删除之前的哈希环
+func testConsistentHashGetNodeWithHistoryDuringDrain(t *testing.T, ring csHash.HashRing) {
+	ctx := context.Background()
+	before := make(map[string]string)
+	var ch *csHash.ConsistentHash
+	checks := 0
+	migrator := func(ctx context.Context, dataKeys map[string]struct{}, from, to string) error {
+		checks++
+		for dataKey, owner := range before {
+			if _, previousNodeName, err := ch.GetNodeWithHistory(ctx, dataKey); err != nil || previousNodeName != owner {
+				return fmt.Errorf("%s previous node expect %s, got %s, err: %v", dataKey, owner, previousNodeName, err)
+			}
+		}
+		return nil
+	}
+	ch = csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), migrator, csHash.WithReplicas(5))
+	for _, nodeName := range []string{"node_a", "node_b", "node_c"} {
+		if err := ch.AddNode(ctx, nodeName, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, err := ch.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[dataKey] = nodeName
+	}
+
+	checks = 0
+	if err := ch.DrainNode(ctx, "node_b"); err != nil {
+		t.Fatal(err)
+	}
+	if checks < 2 {
+		t.Fatalf("expect a check after each virtual node, got %d", checks)
+	}
+	for dataKey, owner := range before {
+		nodeName, previousNodeName, err := ch.GetNodeWithHistory(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previousNodeName != owner || nodeName == "node_b" {
+			t.Fatalf("%s expect previous %s, got (%s, %s)", dataKey, owner, nodeName, previousNodeName)
+		}
+	}
+}
+
+func TestConsistentHashSnapshot(t *testing.T) {
+	testConsistentHashSnapshot(t, skipHashRing.NewSkipListHashRing())
+}
+
+func testConsistentHashSnapshot(t *testing.T, ring csHash.HashRing) {
+	ctx := context.Background()
+	store := csHash.NewMemoryDataKeyStore()
+	locations := make(map[string]string)
+	migrator := func(ctx context.Context, dataKeys map[string]struct{}, from, to string) error {
+		for dataKey := range dataKeys {
+			if locations[dataKey] != from {
+				return fmt.Errorf("%s is on %s, not %s", dataKey, locations[dataKey], from)
+			}
+			locations[dataKey] = to
+		}
+		return nil
+	}
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), migrator,
+		csHash.WithDataKeyStore(store), csHash.WithSnapshot(0))
+	getOwners := func() map[string]string {
+		owners := make(map[string]string)
+		for i := 0; i < 200; i++ {
+			dataKey := fmt.Sprintf("data_%d", i)
+			nodeName, err := ch.GetNode(ctx, dataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			owners[dataKey] = nodeName
+		}
+		return owners
+	}
+
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.AddNode(ctx, "node_b", 1); err != nil {
+		t.Fatal(err)
+	}
+	goodVersion, _ := ring.GetVersion(ctx)
+	goodOwners := getOwners()
+
+	if err := ch.AddNode(ctx, "node_c", 3); err != nil {
+		t.Fatal(err)
+	}
+	badVersion, _ := ring.GetVersion(ctx)
+	badOwners := getOwners()
+	for dataKey, nodeName := range badOwners {
+		locations[dataKey] = nodeName
+		if err := ch.RegisterDataKey(ctx, dataKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//按历史版本查找
+	for dataKey := range goodOwners {
+		if nodeName, err := ch.GetNodeAt(ctx, dataKey, goodVersion); err != nil || nodeName != goodOwners[dataKey] {
+			t.Fatalf("%s at version %d expect %s, got %s, err: %v", dataKey, goodVersion, goodOwners[dataKey], nodeName, err)
+		}
+		if nodeName, err := ch.GetNodeAt(ctx, dataKey, badVersion); err != nil || nodeName != badOwners[dataKey] {
+			t.Fatalf("%s at version %d expect %s, got %s, err: %v", dataKey, badVersion, badOwners[dataKey], nodeName, err)
+		}
+	}
+
+	if err := ch.Rollback(ctx, goodVersion); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := ring.GetVersion(ctx); version != badVersion+1 {
+		t.Fatalf("expect version %d after rollback, got %d", badVersion+1, version)
+	}
+	if nodes, _ := ring.GetRealNodes(ctx); len(nodes) != 2 || nodes["node_c"] != 0 {
+		t.Fatalf("expect node_a and node_b after rollback, got %v", nodes)
+	}
+	for dataKey, nodeName := range getOwners() {
+		if nodeName != goodOwners[dataKey] {
+			t.Fatalf("%s expect %s after rollback, got %s", dataKey, goodOwners[dataKey], nodeName)
+		}
+		if locations[dataKey] != nodeName {
+			t.Fatalf("%s not migrated, located on %s, expect %s", dataKey, locations[dataKey], nodeName)
+		}
+		//回滚之前的版本可以通过快照读取
+		if current, previous, err := ch.GetNodeWithHistory(ctx, dataKey); err != nil || current != nodeName || previous != badOwners[dataKey] {
+			t.Fatalf("%s expect (%s, %s), got (%s, %s), err: %v", dataKey, nodeName, badOwners[dataKey], current, previous, err)
+		}
+	}
+
+	//回滚本身也可以撤销
+	if err := ch.Rollback(ctx, badVersion); err != nil {
+		t.Fatal(err)
+	}
+	for dataKey, nodeName := range getOwners() {
+		if nodeName != badOwners[dataKey] || locations[dataKey] != nodeName {
+			t.Fatalf("%s expect %s after undo, got %s, located on %s", dataKey, badOwners[dataKey], nodeName, locations[dataKey])
+		}
+	}
+
+	if err := ch.Rollback(ctx, 1000); !errors.Is(err, csHash.ErrSnapshotNotExists) {
+		t.Fatalf("expect ErrSnapshotNotExists, got %v", err)
+	}
+}
+
+func TestConsistentHashSnapshotLimit(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	plain := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	if err := plain.Rollback(ctx, 1); !errors.Is(err, csHash.ErrSnapshotNotSupported) {
+		t.Fatalf("expect ErrSnapshotNotSupported, got %v", err)
+	}
+
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithSnapshot(2))
+	for i := 0; i < 5; i++ {
+		if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versions, err := ch.GetSnapshotVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[1] != 5 {
+		t.Fatalf("expect latest 2 snapshots, got %v", versions)
+	}
+}
+
+// 只暴露HashRing接口，用于测试不支持快照、批量修改等可选接口的哈希环
+type plainHashRing struct {
+	csHash.HashRing
+}
+
+// 只暴露HashRing和节点状态接口，用于测试不支持快照、批量修改但保存节点状态的哈希环
+type statefulPlainHashRing struct {
+	csHash.StatefulHashRing
+}
+
+// 哈希环只实现HashRing时，迁移区间、本地缓存和顺时针遍历根据真实节点列表重新计算虚拟节点
+func TestConsistentHashPlainHashRing(t *testing.T) {
+	testConsistentHashMigration(t, plainHashRing{skipHashRing.NewSkipListHashRing()}, csHash.NewMemoryDataKeyStore())
+	testConsistentHashGetNodes(t, plainHashRing{skipHashRing.NewSkipListHashRing()})
+
+	ctx := context.Background()
+	ring := plainHashRing{skipHashRing.NewSkipListHashRing()}
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	cached := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithLocalCache(0))
+	defer cached.Close()
+	for i := 0; i < 3; i++ {
+		if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), int64(i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		expect, _ := ch.GetNode(ctx, dataKey)
+		if nodeName, _ := cached.GetNode(ctx, dataKey); nodeName != expect {
+			t.Fatalf("%s expect %s, got %s", dataKey, expect, nodeName)
+		}
+	}
+	if _, err := ch.Verify(ctx); !errors.Is(err, csHash.ErrRingNotSupported) {
+		t.Fatalf("expect ErrRingNotSupported, got %v", err)
+	}
+
+	//不支持节点状态时所有节点视为正常
+	nodes, err := ch.ListNodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes["node_1"].State != csHash.NodeStateActive || nodes["node_1"].Replicas != 10 {
+		t.Fatalf("unexpected node %+v", nodes["node_1"])
+	}
+	if err := ch.SetNodeState(ctx, "node_1", csHash.NodeStateDown); !errors.Is(err, csHash.ErrRingNotSupported) {
+		t.Fatalf("expect ErrRingNotSupported, got %v", err)
+	}
+	if err := ch.DrainNode(ctx, "node_1"); !errors.Is(err, csHash.ErrRingNotSupported) {
+		t.Fatalf("expect ErrRingNotSupported, got %v", err)
+	}
+	if _, _, err := ch.GetNodeWithHistory(ctx, "data"); !errors.Is(err, csHash.ErrRingNotSupported) {
+		t.Fatalf("expect ErrRingNotSupported, got %v", err)
+	}
+	snapshot, err := ch.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot.RealNodes["node_1"] = csHash.RealNode{Replicas: 10, State: csHash.NodeStateDown}
+	if err := ch.Import(ctx, snapshot); !errors.Is(err, csHash.ErrRingNotSupported) {
+		t.Fatalf("expect ErrRingNotSupported, got %v", err)
+	}
+}
+
+// 收集文档中所有的字段路径，数组元素的字段合并到同一路径下
+func collectKeys(prefix string, value any, keys map[string]struct{}) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			keys[prefix+"."+key] = struct{}{}
+			collectKeys(prefix+"."+key, child, keys)
+		}
+	case []any:
+		for _, child := range v {
+			collectKeys(prefix+"[]", child, keys)
+		}
+	}
+}
+
+// JSON和YAML导出的字段名一致，旧的JSON字段名仍然可以读取
+func TestRingSnapshotFieldNames(t *testing.T) {
+	ctx := context.Background()
+	ch := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil)
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ch.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatKeys := make(map[csHash.SnapshotFormat]map[string]struct{})
+	for _, format := range []csHash.SnapshotFormat{csHash.SnapshotFormatJSON, csHash.SnapshotFormatYAML} {
+		buf := bytes.Buffer{}
+		if err := csHash.WriteRingSnapshot(&buf, snapshot, format); err != nil {
+			t.Fatal(err)
+		}
+		var document map[string]any
+		if format == csHash.SnapshotFormatJSON {
+			err = json.Unmarshal(buf.Bytes(), &document)
+		} else {
+			err = yaml.Unmarshal(buf.Bytes(), &document)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatKeys[format] = make(map[string]struct{})
+		collectKeys("", document, formatKeys[format])
+	}
+	jsonKeys, yamlKeys := formatKeys[csHash.SnapshotFormatJSON], formatKeys[csHash.SnapshotFormatYAML]
+	if !reflect.DeepEqual(jsonKeys, yamlKeys) {
+		t.Fatalf("json keys %v differ from yaml keys %v", jsonKeys, yamlKeys)
+	}
+	for _, key := range []string{".hash_scores[].virtual_nodes[].virtual_node_id", ".hash_scores[].virtual_nodes[].version"} {
+		if _, ok := jsonKeys[key]; !ok {
+			t.Fatalf("expect %s, got %v", key, jsonKeys)
+		}
+	}
+
+	legacy := `{"version": 1, "hash_scores": [{"score": 1024, "virtual_nodes": [{"VirtualNodeID": "node_a_1", "Version": 1}]}]}`
+	decoded, err := csHash.ReadRingSnapshot(strings.NewReader(legacy), csHash.SnapshotFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if virtualNode := decoded.HashScores[0].VirtualNodes[0]; virtualNode.VirtualNodeID != "node_a_1" || virtualNode.Version != 1 {
+		t.Fatalf("unexpected legacy virtual node: %+v", virtualNode)
+	}
+}
+
+func TestConsistentHashExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil)
+	for i := 0; i < 4; i++ {
+		if err := source.AddNode(ctx, fmt.Sprintf("node_%d", i), int64(i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := source.SetNodeState(ctx, "node_3", csHash.NodeStateDown); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := source.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets := map[string]csHash.HashRing{
+		"skip list": skipHashRing.NewSkipListHashRing(),
+		"redis":     redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t)),
+		"plain":     statefulPlainHashRing{skipHashRing.NewSkipListHashRing()},
+	}
+	for _, format := range []csHash.SnapshotFormat{csHash.SnapshotFormatJSON, csHash.SnapshotFormatYAML} {
+		buf := bytes.Buffer{}
+		if err := csHash.WriteRingSnapshot(&buf, snapshot, format); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := csHash.ReadRingSnapshot(&buf, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, snapshot) {
+			t.Fatalf("%s round trip mismatch", format)
+		}
+
+		for name, ring := range targets {
+			target := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+			//目标哈希环上已有的数据会被整体替换
+			if err := target.AddNode(ctx, "stale_node", 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := target.Import(ctx, decoded); err != nil {
+				t.Fatalf("%s import %s failed: %v", name, format, err)
+			}
+			if version, _ := ring.GetVersion(ctx); version < snapshot.Version {
+				t.Fatalf("%s expect version >= %d, got %d", name, snapshot.Version, version)
+			}
+			imported, err := target.Export(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(imported.RealNodes, snapshot.RealNodes) || len(imported.HashScores) != len(snapshot.HashScores) {
+				t.Fatalf("%s import %s mismatch", name, format)
+			}
+			for i := 0; i < 200; i++ {
+				dataKey := fmt.Sprintf("data_%d", i)
+				expect, _ := source.GetNode(ctx, dataKey)
+				if nodeName, _ := target.GetNode(ctx, dataKey); nodeName != expect {
+					t.Fatalf("%s: %s expect %s, got %s", name, dataKey, expect, nodeName)
+				}
+			}
+		}
+	}
+
+	snapshot.HashScores = append(snapshot.HashScores, &csHash.HashScore{Score: 1, VirtualNodes: []csHash.VirtualNode{{VirtualNodeID: "unknown_1"}}})
+	if err := source.Import(ctx, snapshot); err == nil {
+		t.Fatal("expect invalid snapshot error")
+	}
+}
+
+func TestConsistentHashVerify(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	for i := 0; i < 3; i++ {
+		if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if problems, err := ch.Verify(ctx); err != nil || len(problems) != 0 {
+		t.Fatalf("expect no problem, got %v, err: %v", problems, err)
+	}
+
+	//绕过ConsistentHash直接修改哈希环，制造不一致
+	hasher := csHash.NewMurmurHasher32()
+	if err := ring.RemoveVirtualNode(ctx, int64(hasher.Encrypt("node_0_1")), "node_0_1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.AddVirtualNode(ctx, int64(hasher.Encrypt("ghost_1")), "ghost_1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.AddVirtualNode(ctx, 1, "node_1_2"); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := ch.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 3 {
+		t.Fatalf("expect 3 problems, got %v", problems)
+	}
+	for _, problem := range problems {
+		switch {
+		case problem.VirtualNodeID == "ghost_1" && problem.Message == "real node not exists":
+		case problem.VirtualNodeID == "node_1_2" && problem.Score == 1:
+		case problem.NodeName == "node_0" && problem.VirtualNodeID == "":
+		default:
+			t.Fatalf("unexpected problem: %+v", problem)
+		}
+	}
+}
+
+func TestConsistentHashStats(t *testing.T) {
+	ctx := context.Background()
+	stats := func(replicas int64) csHash.RingStats {
+		ch := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil,
+			csHash.WithReplicas(replicas))
+		for i := 0; i < 4; i++ {
+			if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		result, err := ch.Stats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	//映射数量范围为[1,10]，超出上限时按10处理
+	if clamped := stats(500); clamped.VirtualNodes != 40 {
+		t.Fatalf("expect replicas clamped to 10, got %d virtual nodes", clamped.VirtualNodes)
+	}
+
+	few, many := stats(2), stats(10)
+	for _, result := range []csHash.RingStats{few, many} {
+		if len(result.Nodes) != 4 {
+			t.Fatalf("expect 4 nodes, got %+v", result.Nodes)
+		}
+		share, vnodes := 0.0, int64(0)
+		for _, node := range result.Nodes {
+			share += node.Share
+			vnodes += node.VirtualNodes
+			if node.ExpectedShare != 0.25 {
+				t.Fatalf("expect expected share 0.25, got %+v", node)
+			}
+		}
+		if share < 0.9999 || share > 1.0001 || vnodes != result.VirtualNodes {
+			t.Fatalf("share: %f, virtual nodes: %d, stats: %+v", share, vnodes, result)
+		}
+		if result.MaxMinRatio < 1 {
+			t.Fatalf("expect max/min ratio >= 1, got %f", result.MaxMinRatio)
+		}
+	}
+	if many.StdDev >= few.StdDev {
+		t.Fatalf("expect more replicas to be more balanced, got %f >= %f", many.StdDev, few.StdDev)
+	}
+}
+
+// 所有数据key的score都是MinInt64
+type minScoreHasher struct{}
+
+func (minScoreHasher) Encrypt64(origin string) uint64 {
+	return 1 << 63
+}
+
+// 从MinInt64开始顺时针遍历时，一段区间就覆盖整个哈希环，不会再从环首重复遍历
+func TestConsistentHashWalkFromMinScore(t *testing.T) {
+	ctx := context.Background()
+	ring := &countingHashRing{SkipListHashRing: skipHashRing.NewSkipListHashRing()}
+	ch := csHash.NewConsistentHash64(ring, csHash.NewXXHasher64(), nil, csHash.WithReplicas(2))
+	for i := 0; i < 3; i++ {
+		if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect, err := ch.GetNodes(ctx, "data", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minScore := csHash.NewConsistentHash64(ring, minScoreHasher{}, nil)
+	ring.rangeReads = 0
+	nodeNames, err := minScore.GetNodes(ctx, "data", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeNames) != len(expect) || ring.rangeReads != 1 {
+		t.Fatalf("expect %d nodes in 1 range read, got %v in %d range reads", len(expect), nodeNames, ring.rangeReads)
+	}
+}
+
+func TestConsistentHash64(t *testing.T) {
+	ctx := context.Background()
+	for _, encryptor := range []csHash.HashEncryptor64{csHash.NewMurmurHasher128(), csHash.NewXXHasher64()} {
+		ch := csHash.NewConsistentHash64(skipHashRing.NewSkipListHashRing(), encryptor, nil)
+		for i := 0; i < 3; i++ {
+			if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 10); err != nil {
+				t.Fatal(err)
+			}
+		}
+		//score分布在整个int64上
+		snapshot, err := ch.Export(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.HashBits != csHash.HashBits64 || snapshot.HashScores[0].Score >= 0 || snapshot.HashScores[len(snapshot.HashScores)-1].Score <= math.MaxInt32 {
+			t.Fatalf("expect 64 bit scores, got hash bits %d, scores [%d, %d]", snapshot.HashBits,
+				snapshot.HashScores[0].Score, snapshot.HashScores[len(snapshot.HashScores)-1].Score)
+		}
+		if problems, err := ch.Verify(ctx); err != nil || len(problems) != 0 {
+			t.Fatalf("expect no problem, got %v, err: %v", problems, err)
+		}
+		stats, err := ch.Stats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		share := 0.0
+		for _, node := range stats.Nodes {
+			share += node.Share
+		}
+		if share < 0.9999 || share > 1.0001 {
+			t.Fatalf("expect total share 1, got %f", share)
+		}
+		nodeNames, err := ch.GetNodes(ctx, "data", 3)
+		if err != nil || len(nodeNames) != 3 {
+			t.Fatalf("expect 3 nodes, got %v, err: %v", nodeNames, err)
+		}
+
+		//32位和64位的score不能混用
+		ch32 := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil)
+		if err := ch32.Import(ctx, snapshot); err == nil {
+			t.Fatal("expect import 64 bit snapshot into 32 bit ring failed")
+		}
+	}
+}
+
+func TestKetamaConsistentHash(t *testing.T) {
+	ctx := context.Background()
+	migrated := 0
+	ch := csHash.NewKetamaConsistentHash(skipHashRing.NewSkipListHashRing(), func(ctx context.Context, dataKeys map[string]struct{}, from, to string) error {
+		migrated++
+		return nil
+	})
+	assertKetama := func(counts map[string]int64, routes map[string]string) {
+		t.Helper()
+		stats, err := ch.Stats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, node := range stats.Nodes {
+			if node.VirtualNodes != counts[node.Name] {
+				t.Fatalf("node %s, expect %d points, got %d", node.Name, counts[node.Name], node.VirtualNodes)
+			}
+		}
+		if problems, err := ch.Verify(ctx); err != nil || len(problems) != 0 {
+			t.Fatalf("expect no problem, got %v, err: %v", problems, err)
+		}
+		for dataKey, expect := range routes {
+			if nodeName, err := ch.GetNode(ctx, dataKey); err != nil || nodeName != expect {
+				t.Fatalf("data key %s, expect %s, got %s, err: %v", dataKey, expect, nodeName, err)
+			}
+		}
+	}
+
+	//期望结果由独立实现的libketama算法计算
+	for _, nodeName := range []string{"10.0.1.1:11211", "10.0.1.2:11211", "10.0.1.3:11211"} {
+		if err := ch.AddNode(ctx, nodeName, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertKetama(map[string]int64{"10.0.1.1:11211": 160, "10.0.1.2:11211": 160, "10.0.1.3:11211": 160}, map[string]string{
+		"foo": "10.0.1.2:11211", "bar": "10.0.1.1:11211", "user:1": "10.0.1.1:11211", "user:2": "10.0.1.3:11211", "a": "10.0.1.3:11211",
+	})
+	if migrated == 0 {
+		t.Fatal("expect data migrated when adding nodes")
+	}
+
+	//修改权重后所有节点的点数重新计算
+	for nodeName, weight := range map[string]int64{"10.0.1.1:11211": 900, "10.0.1.2:11211": 1000, "10.0.1.3:11211": 300} {
+		if err := ch.UpdateNodeWeight(ctx, nodeName, weight); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertKetama(map[string]int64{"10.0.1.1:11211": 196, "10.0.1.2:11211": 216, "10.0.1.3:11211": 64}, map[string]string{
+		"foo": "10.0.1.2:11211", "user:1": "10.0.1.2:11211", "user:2": "10.0.1.1:11211", "a": "10.0.1.1:11211",
+	})
+
+	if err := ch.RemoveNode(ctx, "10.0.1.3:11211"); err != nil {
+		t.Fatal(err)
+	}
+	assertKetama(map[string]int64{"10.0.1.1:11211": 148, "10.0.1.2:11211": 168}, map[string]string{
+		"foo": "10.0.1.2:11211", "user:1": "10.0.1.1:11211", "user:2": "10.0.1.2:11211", "b": "10.0.1.2:11211",
+	})
+
+	//ketama环不能导入到普通模式
+	snapshot, err := ch.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.Ketama {
+		t.Fatal("expect ketama snapshot")
+	}
+	if err := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMD5Hasher(), nil).Import(ctx, snapshot); err == nil {
+		t.Fatal("expect import ketama snapshot into non ketama ring failed")
+	}
+}
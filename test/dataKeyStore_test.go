@@ -0,0 +1,89 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 11:40:18
+ */
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/redisHashRing"
+)
+
+func TestMemoryDataKeyStore(t *testing.T) {
+	testDataKeyStore(t, csHash.NewMemoryDataKeyStore())
+}
+
+func TestRedisDataKeyStore(t *testing.T) {
+	testDataKeyStore(t, redisHashRing.NewRedisDataKeyStore("test", newMiniRedisClient(t)))
+}
+
+// 区间为(startScore, endScore]，startScore >= endScore时跨越哈希环首尾
+func testDataKeyStore(t *testing.T, store csHash.DataKeyStore) {
+	ctx := context.Background()
+	listKeys := func(startScore, endScore int64) string {
+		t.Helper()
+		dataKeys, err := store.ListKeys(ctx, startScore, endScore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make([]string, 0, len(dataKeys))
+		for dataKey := range dataKeys {
+			result = append(result, dataKey)
+		}
+		sort.Strings(result)
+		return fmt.Sprint(result)
+	}
+
+	if dataKeys := listKeys(0, 100); dataKeys != "[]" {
+		t.Fatalf("empty store, expect no data key, got %s", dataKeys)
+	}
+
+	for dataKey, score := range map[string]int64{"key_10": 10, "key_20": 20, "key_30": 30, "key_neg": -10} {
+		if err := store.RegisterKey(ctx, dataKey, score); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		start, end int64
+		expect     string
+	}{
+		{10, 30, "[key_20 key_30]"},
+		{11, 19, "[]"},
+		{-100, 10, "[key_10 key_neg]"},
+		//跨越哈希环首尾
+		{20, 10, "[key_10 key_30 key_neg]"},
+		{30, -10, "[key_neg]"},
+		//startScore == endScore时为整个哈希环
+		{20, 20, "[key_10 key_20 key_30 key_neg]"},
+	} {
+		if dataKeys := listKeys(c.start, c.end); dataKeys != c.expect {
+			t.Fatalf("(%d, %d] expect %s, got %s", c.start, c.end, c.expect, dataKeys)
+		}
+	}
+
+	//重复登记覆盖原来的score
+	if err := store.RegisterKey(ctx, "key_10", 25); err != nil {
+		t.Fatal(err)
+	}
+	if dataKeys := listKeys(20, 30); dataKeys != "[key_10 key_30]" {
+		t.Fatalf("expect [key_10 key_30], got %s", dataKeys)
+	}
+
+	if err := store.UnregisterKey(ctx, "key_30"); err != nil {
+		t.Fatal(err)
+	}
+	//注销不存在的数据key直接返回
+	if err := store.UnregisterKey(ctx, "key_not_exists"); err != nil {
+		t.Fatal(err)
+	}
+	if dataKeys := listKeys(20, 20); dataKeys != "[key_10 key_20 key_neg]" {
+		t.Fatalf("expect [key_10 key_20 key_neg], got %s", dataKeys)
+	}
+}
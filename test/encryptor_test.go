@@ -0,0 +1,144 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 15:02:37
+ */
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	csHash "github.com/YShiJia/consistentHash"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+)
+
+// 分布测试的数据量和分桶数量
+const (
+	distributionKeys    = 100000
+	distributionBuckets = 64
+)
+
+// FNV-1a和CRC32C的雪崩效应较弱，顺序key的分布明显不如其他算法，只检查不出现严重倾斜
+var weakHashEncryptors = map[string]bool{
+	csHash.HashFNV1a:  true,
+	csHash.HashCRC32C: true,
+}
+
+func newHashEncryptors(t testing.TB) map[string]csHash.HashEncryptor {
+	encryptors := make(map[string]csHash.HashEncryptor)
+	for _, name := range csHash.HashEncryptorNames() {
+		encryptor, err := csHash.NewHashEncryptor(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encryptors[name] = encryptor
+	}
+	return encryptors
+}
+
+func TestNewHashEncryptor(t *testing.T) {
+	if len(newHashEncryptors(t)) != 7 {
+		t.Fatalf("expect 7 hash encryptors, got %v", csHash.HashEncryptorNames())
+	}
+	if _, err := csHash.NewHashEncryptor("unknown"); err == nil {
+		t.Fatal("expect unknown hash encryptor failed")
+	}
+	if _, err := csHash.NewHashEncryptor(csHash.HashXXHash64); err == nil {
+		t.Fatal("expect 64 bit hash encryptor not available as HashEncryptor")
+	}
+	for _, name := range csHash.HashEncryptor64Names() {
+		if _, err := csHash.NewHashEncryptor64(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//不同key的SipHash结果不同
+	encryptor, _ := csHash.NewHashEncryptor(csHash.HashSipHash)
+	keyed, _ := csHash.NewHashEncryptor(csHash.HashSipHash, csHash.WithSipHashKey([16]byte{1}))
+	if encryptor.Encrypt("data") == keyed.Encrypt("data") {
+		t.Fatal("expect different sip hash with different key")
+	}
+	if keyed.Encrypt("data") != csHash.NewSipHasher([16]byte{1}).Encrypt("data") {
+		t.Fatal("expect same sip hash with same key")
+	}
+}
+
+// 顺序key（虚拟节点id、数据key的常见形式）在哈希值域上均匀分布，卡方检验
+func TestHashEncryptorDistribution(t *testing.T) {
+	for name, encryptor := range newHashEncryptors(t) {
+		t.Run(name, func(t *testing.T) {
+			buckets := make([]int64, distributionBuckets)
+			for i := 0; i < distributionKeys; i++ {
+				score := int64(encryptor.Encrypt(fmt.Sprintf("key_%d", i)))
+				if score < 0 || score >= math.MaxInt32 {
+					t.Fatalf("score %d out of range", score)
+				}
+				buckets[score*distributionBuckets/math.MaxInt32]++
+			}
+			expect := float64(distributionKeys) / distributionBuckets
+			chiSquare := 0.0
+			for _, count := range buckets {
+				chiSquare += (float64(count) - expect) * (float64(count) - expect) / expect
+			}
+			t.Logf("chi square: %.2f", chiSquare)
+			//63个自由度，p=0.0001时的临界值约为112
+			limit := 112.0
+			if weakHashEncryptors[name] {
+				limit = 1000
+			}
+			if chiSquare > limit {
+				t.Fatalf("chi square %f, buckets: %v", chiSquare, buckets)
+			}
+		})
+	}
+}
+
+// 作为哈希环的哈希时各节点负载接近，每个节点100个虚拟节点，Load标准差的期望约为0.1
+func TestHashEncryptorRingBalance(t *testing.T) {
+	ctx := context.Background()
+	for name, encryptor := range newHashEncryptors(t) {
+		t.Run(name, func(t *testing.T) {
+			ch := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), encryptor, nil, csHash.WithReplicas(10))
+			for i := 0; i < 8; i++ {
+				if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 10); err != nil {
+					t.Fatal(err)
+				}
+			}
+			stats, err := ch.Stats(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("std dev: %.4f, max/min: %.4f", stats.StdDev, stats.MaxMinRatio)
+			limit := 0.2
+			if weakHashEncryptors[name] {
+				limit = 0.5
+			}
+			if stats.StdDev > limit {
+				t.Fatalf("expect std dev <= %f, got %f", limit, stats.StdDev)
+			}
+		})
+	}
+}
+
+func BenchmarkHashEncryptor(b *testing.B) {
+	for _, name := range csHash.HashEncryptorNames() {
+		encryptor, _ := csHash.NewHashEncryptor(name)
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				encryptor.Encrypt("benchmark_data_key")
+			}
+		})
+	}
+	for _, name := range csHash.HashEncryptor64Names() {
+		encryptor, _ := csHash.NewHashEncryptor64(name)
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				encryptor.Encrypt64("benchmark_data_key")
+			}
+		})
+	}
+}
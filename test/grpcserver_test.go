@@ -0,0 +1,168 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 23:27:40
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/grpcserver"
+	"github.com/YShiJia/consistentHash/grpcserver/pb"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// 通过内存连接启动gRPC服务，返回连接到该服务的客户端
+func newGRPCClient(t *testing.T, ch *csHash.ConsistentHash) *grpcserver.Client {
+	return grpcserver.NewClient(newGRPCConn(t, ch))
+}
+
+// 通过内存连接启动gRPC服务，返回连接到该服务的连接
+func newGRPCConn(t *testing.T, ch *csHash.ConsistentHash) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterConsistentHashServer(server, grpcserver.NewServer(ch))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGRPCServer(t *testing.T) {
+	ctx := context.Background()
+	ch := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil)
+	client := newGRPCClient(t, ch)
+
+	if _, err := client.GetNode(ctx, "data"); !errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Fatalf("expect ErrVirtualNodeNotExists, got %v", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := client.Watch(watchCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, nodeName := range []string{"node_a", "node_b", "node_c"} {
+		if err := client.AddNode(ctx, nodeName, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.AddNode(ctx, "node_a", 1); !errors.Is(err, csHash.ErrNodeAlreadyExists) || csHash.ErrorCode(err) != csHash.ErrNodeAlreadyExistsCode {
+		t.Fatalf("expect ErrNodeAlreadyExists, got %v", err)
+	}
+	for _, nodeName := range []string{"node_a", "node_b", "node_c"} {
+		select {
+		case event := <-events:
+			if event.Type != csHash.RingEventNodeAdded || event.NodeName != nodeName {
+				t.Fatalf("expect %s added, got %+v", nodeName, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("wait %s added event timeout", nodeName)
+		}
+	}
+
+	//远程查找与进程内查找结果一致
+	routers := []csHash.Router{ch, client}
+	for i := 0; i < 100; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		expect, err := routers[0].GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodeName, err := routers[1].GetNode(ctx, dataKey); err != nil || nodeName != expect {
+			t.Fatalf("%s expect %s, got %s, err: %v", dataKey, expect, nodeName, err)
+		}
+		expectNodes, _ := routers[0].GetNodes(ctx, dataKey, 2)
+		nodeNames, err := routers[1].GetNodes(ctx, dataKey, 2)
+		if err != nil || len(nodeNames) != 2 || nodeNames[0] != expectNodes[0] || nodeNames[1] != expectNodes[1] {
+			t.Fatalf("%s expect %v, got %v, err: %v", dataKey, expectNodes, nodeNames, err)
+		}
+	}
+
+	if err := client.DrainNode(ctx, "node_b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RemoveNode(ctx, "node_b"); !errors.Is(err, csHash.ErrNodeNotExists) {
+		t.Fatalf("expect ErrNodeNotExists, got %v", err)
+	}
+	nodes, err := client.ListNodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes["node_a"].Replicas != 5 || nodes["node_c"].State != csHash.NodeStateActive {
+		t.Fatalf("unexpected nodes: %v", nodes)
+	}
+}
+
+func TestGRPCWatchNotSupported(t *testing.T) {
+	ch := csHash.NewConsistentHash(plainHashRing{skipHashRing.NewSkipListHashRing()}, csHash.NewMurmurHasher32(), nil)
+	client := newGRPCClient(t, ch)
+	if _, err := client.Watch(context.Background()); !errors.Is(err, csHash.ErrWatchNotSupported) {
+		t.Fatalf("expect ErrWatchNotSupported, got %v", err)
+	}
+}
+
+// 错误码相同的预定义错误按错误本身映射gRPC状态码，客户端仍能还原出对应的预定义错误
+func TestGRPCServerInvalidVirtualNodeID(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	conn := newGRPCConn(t, ch)
+
+	//虚拟节点id不带编号，无法解析出真实节点
+	if _, err := ring.AddVirtualNode(ctx, 0, "invalid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pb.NewConsistentHashClient(conn).Lookup(ctx, &pb.LookupRequest{Key: "data"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expect InvalidArgument, got %v", err)
+	}
+	_, err := grpcserver.NewClient(conn).GetNode(ctx, "data")
+	if !errors.Is(err, csHash.ErrInvalidVirtualNodeID) || errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Fatalf("expect ErrInvalidVirtualNodeID, got %v", err)
+	}
+}
+
+// n不在[1, maxN]内时返回InvalidArgument，不会按n分配内存
+func TestGRPCServerLookupNBounds(t *testing.T) {
+	ctx := context.Background()
+	ch := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil)
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	client := pb.NewConsistentHashClient(newGRPCConn(t, ch))
+
+	for _, n := range []int32{0, -1, grpcserver.DefaultMaxN + 1, math.MaxInt32} {
+		if _, err := client.LookupN(ctx, &pb.LookupNRequest{Key: "data", N: n}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("n=%d, expect InvalidArgument, got %v", n, err)
+		}
+	}
+	resp, err := client.LookupN(ctx, &pb.LookupNRequest{Key: "data", N: grpcserver.DefaultMaxN})
+	if err != nil || len(resp.GetNodes()) != 1 {
+		t.Fatalf("expect 1 node, got %v, err: %v", resp.GetNodes(), err)
+	}
+}
@@ -0,0 +1,166 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 22:14:51
+ */
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/httpserver"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+)
+
+// 发送请求并解析json响应，返回http状态码
+func doJSON(t *testing.T, method, url, body string, resp any) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil {
+		if err := json.Unmarshal(data, resp); err != nil {
+			t.Fatalf("%s %s: invalid response %s", method, url, data)
+		}
+	}
+	return httpResp.StatusCode
+}
+
+func TestHTTPServer(t *testing.T) {
+	ctx := context.Background()
+	ch := csHash.NewConsistentHash(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), nil)
+	server := httptest.NewServer(httpserver.NewServer(ch, httpserver.WithMaxBatchSize(3), httpserver.WithMaxN(5)))
+	defer server.Close()
+
+	errResp := httpserver.ErrorResponse{}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/lookup?key=data", "", &errResp); status != http.StatusServiceUnavailable || errResp.Code != csHash.ErrVirtualNodeNotExistsCode {
+		t.Fatalf("lookup on empty ring, got %d %+v", status, errResp)
+	}
+
+	version := httpserver.VersionResponse{}
+	for _, name := range []string{"node_a", "node_b", "node_c"} {
+		if status := doJSON(t, http.MethodPost, server.URL+"/v1/nodes", `{"name": "`+name+`", "weight": 1}`, &version); status != http.StatusCreated {
+			t.Fatalf("add %s, got %d", name, status)
+		}
+	}
+	if version.Version != 3 {
+		t.Fatalf("expect version 3, got %d", version.Version)
+	}
+	if status := doJSON(t, http.MethodPost, server.URL+"/v1/nodes", `{"name": "node_a"}`, &errResp); status != http.StatusConflict || errResp.Code != csHash.ErrNodeAlreadyExistsCode || errResp.Message != "node already exists" {
+		t.Fatalf("add duplicated node, got %d %+v", status, errResp)
+	}
+	if status := doJSON(t, http.MethodPost, server.URL+"/v1/nodes", `{"name": 1}`, &errResp); status != http.StatusBadRequest || errResp.Code != httpserver.ErrBadRequestCode {
+		t.Fatalf("invalid body, got %d %+v", status, errResp)
+	}
+
+	result := httpserver.LookupResult{}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/lookup?key=data", "", &result); status != http.StatusOK {
+		t.Fatalf("lookup, got %d", status)
+	}
+	if expect, _ := ch.GetNode(ctx, "data"); len(result.Nodes) != 1 || result.Nodes[0] != expect {
+		t.Fatalf("lookup expect %s, got %v", expect, result.Nodes)
+	}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/lookup?key=data&n=2", "", &result); status != http.StatusOK || len(result.Nodes) != 2 {
+		t.Fatalf("lookup n=2, got %d %v", status, result.Nodes)
+	}
+
+	batch := httpserver.BatchLookupResponse{}
+	if status := doJSON(t, http.MethodPost, server.URL+"/v1/lookup", `{"keys": ["k1", "k2", "k3"]}`, &batch); status != http.StatusOK || len(batch.Results) != 3 {
+		t.Fatalf("batch lookup, got %d %+v", status, batch)
+	}
+	for _, result := range batch.Results {
+		if expect, _ := ch.GetNode(ctx, result.Key); result.Nodes[0] != expect {
+			t.Fatalf("%s expect %s, got %v", result.Key, expect, result.Nodes)
+		}
+	}
+	if status := doJSON(t, http.MethodPost, server.URL+"/v1/lookup", `{"keys": ["k1", "k2", "k3", "k4"]}`, &errResp); status != http.StatusBadRequest {
+		t.Fatalf("batch too large, got %d", status)
+	}
+	//n不在[1, maxN]内时返回参数错误，不会按n分配内存
+	for _, query := range []string{"n=0", "n=-1", "n=6", "n=1099511627776", "n=abc"} {
+		if status := doJSON(t, http.MethodGet, server.URL+"/v1/lookup?key=data&"+query, "", &errResp); status != http.StatusBadRequest {
+			t.Fatalf("lookup %s, got %d", query, status)
+		}
+	}
+	for _, n := range []string{"-1", "6", "1099511627776"} {
+		if status := doJSON(t, http.MethodPost, server.URL+"/v1/lookup", `{"keys": ["k1"], "n": `+n+`}`, &errResp); status != http.StatusBadRequest {
+			t.Fatalf("batch lookup n=%s, got %d", n, status)
+		}
+	}
+	if status := doJSON(t, http.MethodPost, server.URL+"/v1/lookup", `{"keys": ["k1"], "n": 5}`, &batch); status != http.StatusOK || len(batch.Results) != 1 {
+		t.Fatalf("batch lookup n=5, got %d %+v", status, batch)
+	}
+
+	if status := doJSON(t, http.MethodPut, server.URL+"/v1/nodes/node_a/weight", `{"weight": 2}`, &version); status != http.StatusOK {
+		t.Fatalf("update weight, got %d", status)
+	}
+	if status := doJSON(t, http.MethodPut, server.URL+"/v1/nodes/node_x/weight", `{"weight": 2}`, &errResp); status != http.StatusNotFound || errResp.Code != csHash.ErrNodeNotExistsCode {
+		t.Fatalf("update missing node, got %d %+v", status, errResp)
+	}
+	if status := doJSON(t, http.MethodDelete, server.URL+"/v1/nodes/node_b?drain=true", "", &version); status != http.StatusOK {
+		t.Fatalf("drain node, got %d", status)
+	}
+	if status := doJSON(t, http.MethodDelete, server.URL+"/v1/nodes/node_b", "", &errResp); status != http.StatusNotFound {
+		t.Fatalf("remove missing node, got %d", status)
+	}
+
+	nodes := make([]httpserver.Node, 0)
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/nodes", "", &nodes); status != http.StatusOK || len(nodes) != 2 || nodes[0].Name != "node_a" || nodes[0].Replicas != 10 {
+		t.Fatalf("list nodes, got %d %+v", status, nodes)
+	}
+
+	stats := csHash.RingStats{}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/stats", "", &stats); status != http.StatusOK || len(stats.Nodes) != 2 {
+		t.Fatalf("stats, got %d %+v", status, stats)
+	}
+	if share := stats.Nodes[0].Share + stats.Nodes[1].Share; share < 0.9999 || share > 1.0001 {
+		t.Fatalf("expect total share 1, got %f", share)
+	}
+
+	snapshot := csHash.RingSnapshot{}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/ring", "", &snapshot); status != http.StatusOK {
+		t.Fatalf("dump, got %d", status)
+	}
+	if current, _ := ch.Version(ctx); snapshot.Version != current || len(snapshot.RealNodes) != 2 {
+		t.Fatalf("dump mismatch, got %+v", snapshot)
+	}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/version", "", &version); status != http.StatusOK || version.Version != snapshot.Version {
+		t.Fatalf("version, got %d %+v", status, version)
+	}
+}
+
+// 错误码相同的预定义错误按错误本身映射http状态码
+func TestHTTPServerInvalidVirtualNodeID(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	server := httptest.NewServer(httpserver.NewServer(ch))
+	defer server.Close()
+
+	//虚拟节点id不带编号，无法解析出真实节点
+	if _, err := ring.AddVirtualNode(ctx, 0, "invalid"); err != nil {
+		t.Fatal(err)
+	}
+	errResp := httpserver.ErrorResponse{}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/lookup?key=data", "", &errResp); status != http.StatusBadRequest ||
+		errResp.Code != csHash.ErrInvalidVirtualNodeIDCode || errResp.Message != csHash.ErrInvalidVirtualNodeIDMsg {
+		t.Fatalf("lookup with invalid virtual node id, got %d %+v", status, errResp)
+	}
+}
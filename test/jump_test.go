@@ -0,0 +1,167 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 19:40:12
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	csHash "github.com/YShiJia/consistentHash"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+)
+
+func TestJumpHash(t *testing.T) {
+	ctx := context.Background()
+	checker := csHash.NewManualHealthChecker()
+	jump := csHash.NewJumpHash(skipHashRing.NewSkipListHashRing(), csHash.NewXXHasher64(), csHash.WithHealthChecker(checker))
+	if _, err := jump.GetNode(ctx, "data"); !errors.Is(err, csHash.ErrNodeNotExists) {
+		t.Fatalf("expect ErrNodeNotExists, got %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := jump.AddBucket(ctx, fmt.Sprintf("bucket_%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jump.AddBucket(ctx, "bucket_1"); !errors.Is(err, csHash.ErrNodeAlreadyExists) {
+		t.Fatalf("expect ErrNodeAlreadyExists, got %v", err)
+	}
+	buckets, err := jump.Buckets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(buckets) != "[bucket_0 bucket_1 bucket_2 bucket_3]" {
+		t.Fatalf("unexpected buckets %v", buckets)
+	}
+
+	const total = 100000
+	owners := make(map[string]string, total)
+	counts := make(map[string]int)
+	for i := 0; i < total; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, err := jump.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[dataKey] = nodeName
+		counts[nodeName]++
+	}
+	for _, bucketName := range buckets {
+		if counts[bucketName] < total/4*95/100 || counts[bucketName] > total/4*105/100 {
+			t.Fatalf("unbalanced buckets %v", counts)
+		}
+	}
+
+	//追加分片只会把数据迁移到新分片
+	if err := jump.AddBucket(ctx, "bucket_4"); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for dataKey, owner := range owners {
+		nodeName, _ := jump.GetNode(ctx, dataKey)
+		if nodeName == owner {
+			continue
+		}
+		if nodeName != "bucket_4" {
+			t.Fatalf("%s moved from %s to %s", dataKey, owner, nodeName)
+		}
+		moved++
+	}
+	if moved < total/5*95/100 || moved > total/5*105/100 {
+		t.Fatalf("expect about %d keys moved, got %d", total/5, moved)
+	}
+
+	//删除末尾分片后恢复原来的映射
+	if bucketName, err := jump.RemoveLastBucket(ctx); err != nil || bucketName != "bucket_4" {
+		t.Fatalf("expect bucket_4 removed, got %s, %v", bucketName, err)
+	}
+	for dataKey, owner := range owners {
+		if nodeName, _ := jump.GetNode(ctx, dataKey); nodeName != owner {
+			t.Fatalf("%s expect %s, got %s", dataKey, owner, nodeName)
+		}
+	}
+
+	nodeNames, err := jump.GetNodes(ctx, "data_0", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeNames) != 3 || nodeNames[0] != owners["data_0"] {
+		t.Fatalf("unexpected nodes %v", nodeNames)
+	}
+	if nodeNames[0] == nodeNames[1] || nodeNames[1] == nodeNames[2] || nodeNames[0] == nodeNames[2] {
+		t.Fatalf("expect distinct nodes, got %v", nodeNames)
+	}
+	//n远大于分片个数时返回全部分片，不按n分配内存
+	if nodeNames, err := jump.GetNodes(ctx, "data_0", 1<<40); err != nil || len(nodeNames) != 4 {
+		t.Fatalf("expect 4 nodes, got %v, err: %v", nodeNames, err)
+	}
+
+	//不健康的分片顺延到下一个分片
+	checker.MarkDown("bucket_2")
+	for i := 0; i < 1000; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, _ := jump.GetNode(ctx, dataKey)
+		if owners[dataKey] == "bucket_2" {
+			if nodeName != "bucket_3" {
+				t.Fatalf("%s expect bucket_3, got %s", dataKey, nodeName)
+			}
+		} else if nodeName != owners[dataKey] {
+			t.Fatalf("%s moved from %s to %s", dataKey, owners[dataKey], nodeName)
+		}
+	}
+	for _, bucketName := range buckets {
+		checker.MarkDown(bucketName)
+	}
+	if _, err := jump.GetNode(ctx, "data"); !errors.Is(err, csHash.ErrNoAvailableNode) {
+		t.Fatalf("expect ErrNoAvailableNode, got %v", err)
+	}
+}
+
+// 与ConsistentHash一致：读取不跳过下线中的分片，写入跳过下线中的分片，两者都跳过不可用的分片
+func TestJumpHashNodeState(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	jump := csHash.NewJumpHash(ring, csHash.NewXXHasher64())
+	for i := 0; i < 3; i++ {
+		if err := jump.AddBucket(ctx, fmt.Sprintf("bucket_%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owners := make(map[string]string)
+	for i := 0; i < 300; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		owners[dataKey], _ = jump.GetNode(ctx, dataKey)
+	}
+
+	if err := ring.SetRealNodeState(ctx, "bucket_1", csHash.NodeStateDraining); err != nil {
+		t.Fatal(err)
+	}
+	for dataKey, owner := range owners {
+		if nodeName, err := jump.GetNode(ctx, dataKey); err != nil || nodeName != owner {
+			t.Fatalf("%s expect %s, got %s, err: %v", dataKey, owner, nodeName, err)
+		}
+		if nodeName, _ := jump.GetNodeForWrite(ctx, dataKey); nodeName == "bucket_1" {
+			t.Fatalf("%s written to draining bucket_1", dataKey)
+		}
+		if nodeNames, _ := jump.GetNodes(ctx, dataKey, 3); len(nodeNames) != 3 {
+			t.Fatalf("%s expect 3 buckets, got %v", dataKey, nodeNames)
+		}
+		if nodeNames, _ := jump.GetNodesForWrite(ctx, dataKey, 3); len(nodeNames) != 2 {
+			t.Fatalf("%s expect 2 buckets for write, got %v", dataKey, nodeNames)
+		}
+	}
+
+	if err := ring.SetRealNodeState(ctx, "bucket_1", csHash.NodeStateDown); err != nil {
+		t.Fatal(err)
+	}
+	for dataKey := range owners {
+		if nodeName, _ := jump.GetNode(ctx, dataKey); nodeName == "bucket_1" {
+			t.Fatalf("%s read from down bucket_1", dataKey)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 11:05:42
+ */
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/redisHashRing"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+)
+
+func TestSkipListConsistentHashMigration(t *testing.T) {
+	testConsistentHashMigration(t, skipHashRing.NewSkipListHashRing(), csHash.NewMemoryDataKeyStore())
+}
+
+// 每次节点变更后，迁移回调收到的数据key都应该正好是归属发生变化的数据key
+func testConsistentHashMigration(t *testing.T, ring csHash.HashRing, store csHash.DataKeyStore) {
+	ctx := context.Background()
+	owners := make(map[string]string)
+	migrator := func(ctx context.Context, dataKeys map[string]struct{}, from, to string) error {
+		for dataKey := range dataKeys {
+			if owners[dataKey] != from {
+				return fmt.Errorf("data key %s belongs to %s, not %s", dataKey, owners[dataKey], from)
+			}
+			owners[dataKey] = to
+		}
+		return nil
+	}
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), migrator,
+		csHash.WithDataKeyStore(store), csHash.WithRecordOnGetNode())
+
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, err := ch.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[dataKey] = nodeName
+	}
+
+	//weight > 0 添加节点，weight < 0 修改节点权重为-weight，weight == 0 删除节点
+	steps := []struct {
+		nodeName string
+		weight   int64
+	}{{"node_b", 2}, {"node_c", 2}, {"node_a", 0}, {"node_d", 2}, {"node_b", -5}, {"node_c", 0}, {"node_b", -1}}
+	for _, step := range steps {
+		var err error
+		switch {
+		case step.weight > 0:
+			err = ch.AddNode(ctx, step.nodeName, step.weight)
+		case step.weight < 0:
+			err = ch.UpdateNodeWeight(ctx, step.nodeName, -step.weight)
+		default:
+			err = ch.RemoveNode(ctx, step.nodeName)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		//迁移之后，每个数据key都应该在其新的归属节点上
+		for dataKey, owner := range owners {
+			nodeName, err := ch.GetNode(ctx, dataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nodeName != owner {
+				t.Fatalf("data key %s, expect %s, got %s", dataKey, owner, nodeName)
+			}
+		}
+	}
+}
+
+func TestRedisConsistentHashMigration(t *testing.T) {
+	client := newMiniRedisClient(t)
+	testConsistentHashMigration(t, redisHashRing.NewRedisHashRing("test", client), redisHashRing.NewRedisDataKeyStore("test", client))
+}
+
+func TestConsistentHashMigrationRoundTrips(t *testing.T) {
+	ctx := context.Background()
+	ring := &countingHashRing{SkipListHashRing: skipHashRing.NewSkipListHashRing()}
+	migrator := func(ctx context.Context, dataKeys map[string]struct{}, from, to string) error {
+		return nil
+	}
+	plain := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), migrator, csHash.WithDataKeyStore(csHash.NewMemoryDataKeyStore()))
+
+	//没有迁移回调时不需要计算迁移区间
+	if err := plain.AddNode(ctx, "node_a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if ring.pointReads != 0 || ring.fullReads != 0 {
+		t.Fatalf("expect no reads without migrator, got %d point reads, %d full reads", ring.pointReads, ring.fullReads)
+	}
+
+	//每次节点变更只读取一次整个哈希环
+	for i, change := range []func() error{
+		func() error { return ch.AddNode(ctx, "node_b", 10) },
+		func() error { return ch.UpdateNodeWeight(ctx, "node_b", 5) },
+		func() error { return ch.RemoveNode(ctx, "node_a") },
+	} {
+		ring.pointReads, ring.fullReads = 0, 0
+		if err := change(); err != nil {
+			t.Fatal(err)
+		}
+		if ring.pointReads != 0 || ring.fullReads != 1 {
+			t.Fatalf("change %d: expect 1 full read, got %d point reads, %d full reads", i, ring.pointReads, ring.fullReads)
+		}
+	}
+}
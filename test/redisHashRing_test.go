@@ -0,0 +1,347 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 12:05:44
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	csHash "github.com/YShiJia/consistentHash"
+	"github.com/YShiJia/consistentHash/redisHashRing"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newMiniRedisClient(t *testing.T) *redisHashRing.Client {
+	s := miniredis.RunT(t)
+	return redisHashRing.NewClient("tcp", s.Addr(), "")
+}
+
+func TestRedisHashRingVirtualNode(t *testing.T) {
+	ctx := context.Background()
+	ring := redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t))
+
+	if version, err := ring.GetVersion(ctx); err != nil || version != 0 {
+		t.Fatalf("new ring, expect version 0, got %d, err: %v", version, err)
+	}
+	if version, err := ring.AddVirtualNode(ctx, 10, "node_1"); err != nil || version != 1 {
+		t.Fatalf("expect version 1, got %d, err: %v", version, err)
+	}
+	if version, err := ring.AddVirtualNode(ctx, 10, "other_1"); err != nil || version != 2 {
+		t.Fatalf("expect version 2, got %d, err: %v", version, err)
+	}
+	//重复添加返回原有版本号，不修改哈希环
+	if version, err := ring.AddVirtualNode(ctx, 10, "node_1"); err != nil || version != 1 {
+		t.Fatalf("expect version 1, got %d, err: %v", version, err)
+	}
+
+	hashScore, err := ring.GetVirtualNode(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashScore.VirtualNodes) != 2 || hashScore.VirtualNodes[0].VirtualNodeID != "node_1" {
+		t.Fatalf("unexpected hash score: %+v", hashScore)
+	}
+
+	if err := ring.RemoveVirtualNode(ctx, 10, "node_1"); err != nil {
+		t.Fatal(err)
+	}
+	if virtualNodeID, _ := ring.FindDataToVirtualNode(ctx, 20); virtualNodeID != "other_1" {
+		t.Fatalf("expect other_1, got %s", virtualNodeID)
+	}
+	//删除不存在的虚拟节点不修改版本号
+	if err := ring.RemoveVirtualNode(ctx, 10, "node_1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.RemoveVirtualNode(ctx, 10, "other_1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.GetVirtualNode(ctx, 10); !errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Fatalf("expect ErrVirtualNodeNotExists, got %v", err)
+	}
+	if version, _ := ring.GetVersion(ctx); version != 4 {
+		t.Fatalf("expect version 4, got %d", version)
+	}
+}
+
+func TestRedisHashRingBatch(t *testing.T) {
+	ctx := context.Background()
+	ring := redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t))
+
+	virtualNodes := []csHash.VirtualNodeEntry{{Score: 10, VirtualNodeID: "node_1"}, {Score: 20, VirtualNodeID: "node_2"}, {Score: 10, VirtualNodeID: "node_3"}}
+	//整批只增加一次版本号
+	if version, err := ring.AddVirtualNodes(ctx, virtualNodes); err != nil || version != 1 {
+		t.Fatalf("expect version 1, got %d, err: %v", version, err)
+	}
+	if version, err := ring.AddVirtualNodes(ctx, virtualNodes); err != nil || version != 1 {
+		t.Fatalf("nothing changed, expect version 1, got %d, err: %v", version, err)
+	}
+	hashScore, err := ring.GetVirtualNode(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashScore.VirtualNodes) != 2 || hashScore.VirtualNodes[1].VirtualNodeID != "node_3" || hashScore.VirtualNodes[1].Version != 1 {
+		t.Fatalf("unexpected hash score: %+v", hashScore)
+	}
+
+	if version, err := ring.RemoveVirtualNodes(ctx, virtualNodes[:2]); err != nil || version != 2 {
+		t.Fatalf("expect version 2, got %d, err: %v", version, err)
+	}
+	if virtualNodeID, _ := ring.FindDataToVirtualNode(ctx, 15); virtualNodeID != "node_3" {
+		t.Fatalf("expect node_3, got %s", virtualNodeID)
+	}
+}
+
+func TestRedisGetNodeWithoutLock(t *testing.T) {
+	ctx := context.Background()
+	ring := redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t))
+	ch := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	if err := ch.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	//哈希环被锁住时仍然可以查找
+	if err := ring.Lock(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Unlock(ctx)
+	if nodeName, err := ch.GetNode(ctx, "data"); err != nil || nodeName != "node_a" {
+		t.Fatalf("expect node_a, got %s, err: %v", nodeName, err)
+	}
+}
+
+func TestRedisLocalCache(t *testing.T) {
+	ctx := context.Background()
+	ring := redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t))
+	admin := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil)
+	cached := csHash.NewConsistentHash(ring, csHash.NewMurmurHasher32(), nil, csHash.WithLocalCache(0))
+	defer cached.Close()
+
+	for _, nodeName := range []string{"node_a", "node_b", "node_c"} {
+		if err := admin.AddNode(ctx, nodeName, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertSameRouting(t, admin, cached)
+}
+
+func TestRedisWatch(t *testing.T) {
+	testConsistentHashWatch(t, redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t)))
+}
+
+func TestRedisLocalCacheWatch(t *testing.T) {
+	ctx := context.Background()
+	client := newMiniRedisClient(t)
+	admin := csHash.NewConsistentHash(redisHashRing.NewRedisHashRing("test", client), csHash.NewMurmurHasher32(), nil)
+	//不轮询，只依赖修改通知刷新
+	cached := csHash.NewConsistentHash(redisHashRing.NewRedisHashRing("test", client), csHash.NewMurmurHasher32(), nil, csHash.WithLocalCache(0))
+	defer cached.Close()
+
+	if err := admin.AddNode(ctx, "node_a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.GetNode(ctx, "data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.AddNode(ctx, "node_b", 3); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	assertSameRouting(t, admin, cached)
+}
+
+func TestRedisGetNodes(t *testing.T) {
+	testConsistentHashGetNodes(t, redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t)))
+}
+
+func TestRedisDrainNode(t *testing.T) {
+	client := newMiniRedisClient(t)
+	testConsistentHashDrainNode(t, redisHashRing.NewRedisHashRing("test", client), redisHashRing.NewRedisDataKeyStore("test", client))
+}
+
+func TestRedisGetNodeWithHistory(t *testing.T) {
+	testConsistentHashGetNodeWithHistory(t, redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t)))
+}
+
+func TestRedisGetNodeWithHistoryDuringDrain(t *testing.T) {
+	testConsistentHashGetNodeWithHistoryDuringDrain(t, redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t)))
+}
+
+func TestRedisSnapshot(t *testing.T) {
+	testConsistentHashSnapshot(t, redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t)))
+}
+
+func TestRedisHashRingScore64(t *testing.T) {
+	ctx := context.Background()
+	ring := redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t))
+
+	//base、base+1、base+3转换为double后相等
+	const base = int64(1) << 60
+	for _, entry := range []csHash.VirtualNodeEntry{{Score: base + 3, VirtualNodeID: "a_1"}, {Score: base + 1, VirtualNodeID: "b_1"}, {Score: -5, VirtualNodeID: "c_1"}} {
+		if _, err := ring.AddVirtualNode(ctx, entry.Score, entry.VirtualNodeID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if hashScore, err := ring.GetVirtualNode(ctx, base+1); err != nil || hashScore.Score != base+1 || hashScore.VirtualNodes[0].VirtualNodeID != "b_1" {
+		t.Fatalf("expect b_1 at %d, got %+v, err: %v", base+1, hashScore, err)
+	}
+	if _, err := ring.GetVirtualNode(ctx, base+2); !errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Fatalf("expect ErrVirtualNodeNotExists, got %v", err)
+	}
+	for dataScore, expect := range map[int64]string{base: "b_1", base + 2: "a_1", base + 4: "c_1", 0: "b_1"} {
+		if virtualNodeID, err := ring.FindDataToVirtualNode(ctx, dataScore); err != nil || virtualNodeID != expect {
+			t.Fatalf("data score %d, expect %s, got %s, err: %v", dataScore, expect, virtualNodeID, err)
+		}
+	}
+	if hashScores, err := ring.RangeVirtualNodes(ctx, base+2, 1); err != nil || len(hashScores) != 1 || hashScores[0].Score != base+3 {
+		t.Fatalf("expect score %d, got %v, err: %v", base+3, hashScores, err)
+	}
+	if hashScores, err := ring.RangeVirtualNodes(ctx, math.MinInt64, 2); err != nil || len(hashScores) != 2 || hashScores[1].Score != base+1 {
+		t.Fatalf("expect scores [-5 %d], got %v, err: %v", base+1, hashScores, err)
+	}
+	hashScores, _, err := ring.GetAllVirtualNodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashScores) != 3 || hashScores[0].Score != -5 || hashScores[1].Score != base+1 || hashScores[2].Score != base+3 {
+		t.Fatalf("unexpected hash scores: %v", hashScores)
+	}
+	if operation, err := ring.GetLastOperation(ctx); err != nil || operation.Changes[len(operation.Changes)-1].VirtualNodes[0].Score != -5 {
+		t.Fatalf("unexpected last operation: %+v, err: %v", operation, err)
+	}
+
+	startVersion, _ := ring.GetVersion(ctx)
+	if err := ring.BeginOperation(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.RemoveVirtualNode(ctx, base+1, "b_1"); err != nil {
+		t.Fatal(err)
+	}
+	if operation, err := ring.GetLastOperation(ctx); err != nil || operation.StartVersion != startVersion ||
+		len(operation.Changes) != 1 || operation.Changes[0].Type != csHash.RingChangeRemove {
+		t.Fatalf("unexpected last operation: %+v, err: %v", operation, err)
+	}
+	if hashScore, err := ring.GetVirtualNode(ctx, base+3); err != nil || hashScore.VirtualNodes[0].VirtualNodeID != "a_1" {
+		t.Fatalf("expect a_1, got %+v, err: %v", hashScore, err)
+	}
+}
+
+// 64位score超过double精度时，按精确score过滤区间，区间端点取到int64边界时不会溢出
+func TestRedisDataKeyStore64(t *testing.T) {
+	ctx := context.Background()
+	store := redisHashRing.NewRedisDataKeyStore("test", newMiniRedisClient(t))
+	base := int64(1) << 62
+	scores := map[string]int64{
+		"base":   base,
+		"base+1": base + 1,
+		"base+2": base + 2,
+		"max":    math.MaxInt64,
+		"min":    math.MinInt64,
+	}
+	for dataKey, score := range scores {
+		if err := store.RegisterKey(ctx, dataKey, score); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		start, end int64
+		expect     []string
+	}{
+		{base, base + 1, []string{"base+1"}},
+		{base - 1, base, []string{"base"}},
+		{base + 1, base + 2, []string{"base+2"}},
+		{math.MaxInt64 - 1, math.MaxInt64, []string{"max"}},
+		//跨越首尾，startScore为MaxInt64
+		{math.MaxInt64, math.MinInt64, []string{"min"}},
+		{base + 2, base, []string{"max", "min", "base"}},
+	} {
+		dataKeys, err := store.ListKeys(ctx, c.start, c.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dataKeys) != len(c.expect) {
+			t.Fatalf("(%d, %d] expect %v, got %v", c.start, c.end, c.expect, dataKeys)
+		}
+		for _, dataKey := range c.expect {
+			if _, ok := dataKeys[dataKey]; !ok {
+				t.Fatalf("(%d, %d] expect %v, got %v", c.start, c.end, c.expect, dataKeys)
+			}
+		}
+	}
+
+	if err := store.UnregisterKey(ctx, "base+1"); err != nil {
+		t.Fatal(err)
+	}
+	if dataKeys, err := store.ListKeys(ctx, base, base+1); err != nil || len(dataKeys) != 0 {
+		t.Fatalf("expect no data key, got %v, err: %v", dataKeys, err)
+	}
+}
+
+// 哈希环中已有的旧格式虚拟节点仍然可以读取和修改，修改后按新格式写回
+func TestRedisLegacyVirtualNode(t *testing.T) {
+	ctx := context.Background()
+	client := newMiniRedisClient(t)
+	ring := redisHashRing.NewRedisHashRing("legacy", client)
+	legacy := `{"score":100,"virtual_nodes":[{"VirtualNodeID":"a_1","Version":1}]}`
+	if err := client.ZAdd(ctx, "redis:consistent_hash:ring:legacy", 100, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if virtualNodeID, err := ring.FindDataToVirtualNode(ctx, 50); err != nil || virtualNodeID != "a_1" {
+		t.Fatalf("expect a_1, got %s, err: %v", virtualNodeID, err)
+	}
+	if version, err := ring.AddVirtualNode(ctx, 100, "a_1"); err != nil || version != 1 {
+		t.Fatalf("expect existing version 1, got %d, err: %v", version, err)
+	}
+	if _, err := ring.AddVirtualNode(ctx, 100, "b_1"); err != nil {
+		t.Fatal(err)
+	}
+	hashScore, err := ring.GetVirtualNode(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashScore.VirtualNodes) != 2 || hashScore.VirtualNodes[0] != (csHash.VirtualNode{VirtualNodeID: "a_1", Version: 1}) ||
+		hashScore.VirtualNodes[1].VirtualNodeID != "b_1" {
+		t.Fatalf("unexpected virtual nodes: %+v", hashScore.VirtualNodes)
+	}
+	members, err := client.ZRangeByScore(ctx, "redis:consistent_hash:ring:legacy", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || strings.Contains(members[0].Val, "VirtualNodeID") || !strings.Contains(members[0].Val, `"virtual_node_id":"a_1"`) {
+		t.Fatalf("expect virtual nodes written back in the new format, got %v", members)
+	}
+	if err := ring.RemoveVirtualNode(ctx, 100, "a_1"); err != nil {
+		t.Fatal(err)
+	}
+	if virtualNodeID, err := ring.FindDataToVirtualNode(ctx, 50); err != nil || virtualNodeID != "b_1" {
+		t.Fatalf("expect b_1, got %s, err: %v", virtualNodeID, err)
+	}
+}
+
+func TestRedisConsistentHash64(t *testing.T) {
+	ctx := context.Background()
+	expect := csHash.NewConsistentHash64(skipHashRing.NewSkipListHashRing(), csHash.NewXXHasher64(), nil)
+	actual := csHash.NewConsistentHash64(redisHashRing.NewRedisHashRing("test", newMiniRedisClient(t)), csHash.NewXXHasher64(), nil)
+	for i := 0; i < 4; i++ {
+		for _, ch := range []*csHash.ConsistentHash{expect, actual} {
+			if err := ch.AddNode(ctx, fmt.Sprintf("node_%d", i), 3); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	assertSameRouting(t, expect, actual)
+	if problems, err := actual.Verify(ctx); err != nil || len(problems) != 0 {
+		t.Fatalf("expect no problem, got %v, err: %v", problems, err)
+	}
+}
@@ -0,0 +1,167 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-19 20:48:03
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	csHash "github.com/YShiJia/consistentHash"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+)
+
+func TestRendezvous(t *testing.T) {
+	ctx := context.Background()
+	checker := csHash.NewManualHealthChecker()
+	hrw := csHash.NewRendezvous(skipHashRing.NewSkipListHashRing(), csHash.NewMurmurHasher32(), csHash.WithHealthChecker(checker))
+	if _, err := hrw.GetNode(ctx, "data"); !errors.Is(err, csHash.ErrNodeNotExists) {
+		t.Fatalf("expect ErrNodeNotExists, got %v", err)
+	}
+	for i, weight := range []int64{1, 1, 2} {
+		if err := hrw.AddNode(ctx, fmt.Sprintf("node_%d", i), weight); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hrw.AddNode(ctx, "node_0", 1); !errors.Is(err, csHash.ErrNodeAlreadyExists) {
+		t.Fatalf("expect ErrNodeAlreadyExists, got %v", err)
+	}
+
+	//数据量与权重成正比
+	const total = 100000
+	owners := make(map[string]string, total)
+	counts := make(map[string]int)
+	for i := 0; i < total; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, err := hrw.GetNode(ctx, dataKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[dataKey] = nodeName
+		counts[nodeName]++
+	}
+	for nodeName, expect := range map[string]int{"node_0": total / 4, "node_1": total / 4, "node_2": total / 2} {
+		if counts[nodeName] < expect*95/100 || counts[nodeName] > expect*105/100 {
+			t.Fatalf("unbalanced nodes %v", counts)
+		}
+	}
+
+	//副本按得分排序，第一个就是GetNode的结果
+	for i := 0; i < 100; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeNames, err := hrw.GetNodes(ctx, dataKey, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodeNames) != 3 || nodeNames[0] != owners[dataKey] {
+			t.Fatalf("%s unexpected nodes %v", dataKey, nodeNames)
+		}
+		if nodeNames[0] == nodeNames[1] || nodeNames[1] == nodeNames[2] || nodeNames[0] == nodeNames[2] {
+			t.Fatalf("expect distinct nodes, got %v", nodeNames)
+		}
+	}
+
+	//不健康节点上的数据落到得分第二的节点，其余数据不受影响
+	checker.MarkDown("node_2")
+	for i := 0; i < 1000; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		nodeName, _ := hrw.GetNode(ctx, dataKey)
+		if owners[dataKey] != "node_2" && nodeName != owners[dataKey] {
+			t.Fatalf("%s moved from %s to %s", dataKey, owners[dataKey], nodeName)
+		}
+		if nodeName == "node_2" {
+			t.Fatalf("%s routed to unhealthy node", dataKey)
+		}
+	}
+	checker.MarkUp("node_2")
+
+	//删除节点只迁移该节点上的数据
+	if err := hrw.RemoveNode(ctx, "node_0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := hrw.RemoveNode(ctx, "node_0"); !errors.Is(err, csHash.ErrNodeNotExists) {
+		t.Fatalf("expect ErrNodeNotExists, got %v", err)
+	}
+	for dataKey, owner := range owners {
+		nodeName, _ := hrw.GetNode(ctx, dataKey)
+		if owner != "node_0" && nodeName != owner {
+			t.Fatalf("%s moved from %s to %s", dataKey, owner, nodeName)
+		}
+	}
+
+	//调高权重只会让数据迁入该节点
+	if err := hrw.UpdateNodeWeight(ctx, "node_1", 2); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for dataKey, owner := range owners {
+		if owner == "node_0" {
+			continue
+		}
+		nodeName, _ := hrw.GetNode(ctx, dataKey)
+		if nodeName != owner {
+			if nodeName != "node_1" {
+				t.Fatalf("%s moved from %s to %s", dataKey, owner, nodeName)
+			}
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("expect some keys moved to node_1")
+	}
+
+	checker.MarkDown("node_1")
+	checker.MarkDown("node_2")
+	if _, err := hrw.GetNode(ctx, "data"); !errors.Is(err, csHash.ErrNoAvailableNode) {
+		t.Fatalf("expect ErrNoAvailableNode, got %v", err)
+	}
+}
+
+// 与ConsistentHash一致：读取不跳过下线中的节点，写入跳过下线中的节点，两者都跳过不可用的节点
+func TestRendezvousNodeState(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+	hrw := csHash.NewRendezvous(ring, csHash.NewMurmurHasher32())
+	for i := 0; i < 3; i++ {
+		if err := hrw.AddNode(ctx, fmt.Sprintf("node_%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owners := make(map[string]string)
+	for i := 0; i < 300; i++ {
+		dataKey := fmt.Sprintf("data_%d", i)
+		owners[dataKey], _ = hrw.GetNode(ctx, dataKey)
+	}
+
+	if err := ring.SetRealNodeState(ctx, "node_1", csHash.NodeStateDraining); err != nil {
+		t.Fatal(err)
+	}
+	for dataKey, owner := range owners {
+		if nodeName, err := hrw.GetNode(ctx, dataKey); err != nil || nodeName != owner {
+			t.Fatalf("%s expect %s, got %s, err: %v", dataKey, owner, nodeName, err)
+		}
+		if nodeName, _ := hrw.GetNodeForWrite(ctx, dataKey); nodeName == "node_1" {
+			t.Fatalf("%s written to draining node_1", dataKey)
+		}
+		if nodeNames, _ := hrw.GetNodes(ctx, dataKey, 3); len(nodeNames) != 3 {
+			t.Fatalf("%s expect 3 nodes, got %v", dataKey, nodeNames)
+		}
+		if nodeNames, _ := hrw.GetNodesForWrite(ctx, dataKey, 3); len(nodeNames) != 2 {
+			t.Fatalf("%s expect 2 nodes for write, got %v", dataKey, nodeNames)
+		}
+	}
+
+	if err := ring.SetRealNodeState(ctx, "node_1", csHash.NodeStateDown); err != nil {
+		t.Fatal(err)
+	}
+	for dataKey := range owners {
+		if nodeName, _ := hrw.GetNode(ctx, dataKey); nodeName == "node_1" {
+			t.Fatalf("%s read from down node_1", dataKey)
+		}
+	}
+}
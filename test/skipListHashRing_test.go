@@ -0,0 +1,96 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 11:02:15
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	csHash "github.com/YShiJia/consistentHash"
+	skipHashRing "github.com/YShiJia/consistentHash/skipListHashRing"
+)
+
+func TestSkipListHashRing(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+
+	if _, err := ring.FindDataToVirtualNode(ctx, 1); !errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Fatalf("empty ring, expect ErrVirtualNodeNotExists, got %v", err)
+	}
+
+	for _, score := range []int64{30, 10, 20} {
+		if _, err := ring.AddVirtualNode(ctx, score, fmt.Sprintf("node_%d", score)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//同一score上添加第二个虚拟节点，首元素仍然生效
+	if _, err := ring.AddVirtualNode(ctx, 20, "other_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[int64]string{5: "node_10", 10: "node_10", 15: "node_20", 25: "node_30", 31: "node_10"}
+	for dataScore, expect := range cases {
+		virtualNodeID, err := ring.FindDataToVirtualNode(ctx, dataScore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if virtualNodeID != expect {
+			t.Errorf("score %d, expect %s, got %s", dataScore, expect, virtualNodeID)
+		}
+	}
+
+	if err := ring.RemoveVirtualNode(ctx, 20, "node_20"); err != nil {
+		t.Fatal(err)
+	}
+	if virtualNodeID, _ := ring.FindDataToVirtualNode(ctx, 15); virtualNodeID != "other_1" {
+		t.Errorf("expect other_1, got %s", virtualNodeID)
+	}
+	if err := ring.RemoveVirtualNode(ctx, 20, "other_1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.GetVirtualNode(ctx, 20); !errors.Is(err, csHash.ErrVirtualNodeNotExists) {
+		t.Errorf("expect ErrVirtualNodeNotExists, got %v", err)
+	}
+	if virtualNodeID, _ := ring.FindDataToVirtualNode(ctx, 15); virtualNodeID != "node_30" {
+		t.Errorf("expect node_30, got %s", virtualNodeID)
+	}
+
+	//4次添加，2次删除
+	if version, _ := ring.GetVersion(ctx); version != 6 {
+		t.Errorf("expect version 6, got %d", version)
+	}
+	if num := ring.VirtualNodeNum(); num != 2 {
+		t.Errorf("expect 2 virtual nodes, got %d", num)
+	}
+}
+
+func TestSkipListHashRingLock(t *testing.T) {
+	ctx := context.Background()
+	ring := skipHashRing.NewSkipListHashRing()
+
+	if err := ring.Lock(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := ring.Lock(timeoutCtx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect lock timeout, got %v", err)
+	}
+	//锁过期之后可以被重新获取
+	if err := ring.Lock(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Unlock(ctx); !errors.Is(err, skipHashRing.ErrLockNotHeld) {
+		t.Fatalf("expect ErrLockNotHeld, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 20:31:27
+ */
+
+package csHash
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// 哈希环数据不一致的一处问题
+type RingProblem struct {
+	NodeName      string `json:"node_name,omitempty"`
+	VirtualNodeID string `json:"virtual_node_id,omitempty"`
+	Score         int64  `json:"score,omitempty"`
+	Message       string `json:"message"`
+}
+
+// 检查哈希环数据是否一致，返回发现的所有问题，没有问题时返回空列表
+// 检查项：虚拟节点id合法、所属真实节点存在、score与当前哈希算法一致、编号不超过映射数量、真实节点的虚拟节点齐全
+// 需要读取哈希环上实际的虚拟节点，哈希环未实现ListableHashRing时返回ErrRingNotSupported
+func (c *ConsistentHash) Verify(ctx context.Context) ([]RingProblem, error) {
+	if _, ok := c.hashRing.(ListableHashRing); !ok {
+		return nil, ErrRingNotSupported
+	}
+	snapshot, err := c.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := c.virtualNodeCounts(snapshot.RealNodes)
+	problems := make([]RingProblem, 0)
+	indexes := make(map[string]map[int64]struct{})
+	for _, hashScore := range snapshot.HashScores {
+		for _, virtualNode := range hashScore.VirtualNodes {
+			problem := RingProblem{VirtualNodeID: virtualNode.VirtualNodeID, Score: hashScore.Score}
+			nodeName, index, err := parseVirtualNodeID(virtualNode.VirtualNodeID)
+			if err != nil {
+				problem.Message = "invalid virtual node id"
+				problems = append(problems, problem)
+				continue
+			}
+			problem.NodeName = nodeName
+
+			if score := c.virtualNodeScore(nodeName, index); score != hashScore.Score {
+				problem.Message = fmt.Sprintf("score mismatch, expect %d", score)
+				problems = append(problems, problem)
+			}
+			if _, ok := snapshot.RealNodes[nodeName]; !ok {
+				problem.Message = "real node not exists"
+				problems = append(problems, problem)
+				continue
+			}
+			if index < 1 || index > counts[nodeName] {
+				problem.Message = fmt.Sprintf("index out of range [1, %d]", counts[nodeName])
+				problems = append(problems, problem)
+				continue
+			}
+			if indexes[nodeName] == nil {
+				indexes[nodeName] = make(map[int64]struct{})
+			}
+			indexes[nodeName][index] = struct{}{}
+		}
+	}
+
+	nodeNames := make([]string, 0, len(snapshot.RealNodes))
+	for nodeName := range snapshot.RealNodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	for _, nodeName := range nodeNames {
+		replicas := counts[nodeName]
+		if missing := replicas - int64(len(indexes[nodeName])); missing > 0 {
+			problems = append(problems, RingProblem{
+				NodeName: nodeName,
+				Message:  fmt.Sprintf("%d of %d virtual nodes missing", missing, replicas),
+			})
+		}
+	}
+	return problems, nil
+}
+
+// 每个真实节点应有的虚拟节点个数，ketama模式下由所有节点的权重共同决定
+func (c *ConsistentHash) virtualNodeCounts(nodes map[string]RealNode) map[string]int64 {
+	counts := make(map[string]int64, len(nodes))
+	for nodeName, node := range nodes {
+		counts[nodeName] = node.Replicas
+	}
+	if c.ketama {
+		return ketamaPointCounts(counts)
+	}
+	return counts
+}
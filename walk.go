@@ -0,0 +1,141 @@
+/**
+ * @author ysj
+ * @email 2239831438@qq.com
+ * @date 2026-10-18 15:06:33
+ */
+
+package csHash
+
+import (
+	"context"
+	"math"
+)
+
+// 不使用本地缓存时，每次从哈希环上读取的score数量
+const walkPageSize = 64
+
+// 从dataScore开始顺时针遍历哈希环，每个真实节点只访问一次，visit返回false时停止遍历
+// 同一score上只有首个虚拟节点生效，遍历一圈后结束
+func (c *ConsistentHash) walkNodes(ctx context.Context, dataScore int64, visit func(nodeName string) bool) error {
+	seen := make(map[string]struct{})
+	return c.walkHashScores(ctx, dataScore, c.cache != nil, func(hashScore *HashScore) (bool, error) {
+		if len(hashScore.VirtualNodes) == 0 {
+			return true, nil
+		}
+		nodeName, _, err := parseVirtualNodeID(hashScore.VirtualNodes[0].VirtualNodeID)
+		if err != nil {
+			return false, err
+		}
+		if _, ok := seen[nodeName]; ok {
+			return true, nil
+		}
+		seen[nodeName] = struct{}{}
+		return visit(nodeName), nil
+	})
+}
+
+// 从dataScore开始顺时针遍历哈希环上的score，visit返回false或出错时停止遍历，遍历一圈后结束
+// useCache为true时遍历本地副本，否则分页读取哈希环，哈希环未实现RangeHashRing时读取整个哈希环后遍历
+// 哈希环为空时返回ErrVirtualNodeNotExists
+func (c *ConsistentHash) walkHashScores(ctx context.Context, dataScore int64, useCache bool, visit func(hashScore *HashScore) (bool, error)) error {
+	rangeRing, ok := c.hashRing.(RangeHashRing)
+	if useCache || !ok {
+		var view *ringView
+		if useCache {
+			var err error
+			if view, err = c.cache.get(ctx); err != nil {
+				return err
+			}
+		} else {
+			hashScores, version, err := c.getAllVirtualNodes(ctx)
+			if err != nil {
+				return err
+			}
+			view = newRingView(version, hashScores, nil)
+		}
+		if len(view.hashScores) == 0 {
+			return ErrVirtualNodeNotExists
+		}
+		start := view.ceilingIndex(dataScore)
+		for i := 0; i < len(view.hashScores); i++ {
+			next, err := visit(view.hashScores[(start+i)%len(view.hashScores)])
+			if err != nil || !next {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// 先从dataScore走到环尾，再从环首走回dataScore；dataScore为MinInt64时第一段已经覆盖整个哈希环
+	visited := false
+	ranges := [][2]int64{{dataScore, math.MaxInt64}}
+	if dataScore > math.MinInt64 {
+		ranges = append(ranges, [2]int64{math.MinInt64, dataScore - 1})
+	}
+	for _, scoreRange := range ranges {
+		for startScore := scoreRange[0]; ; {
+			hashScores, err := rangeRing.RangeVirtualNodes(ctx, startScore, walkPageSize)
+			if err != nil {
+				return err
+			}
+			for _, hashScore := range hashScores {
+				if hashScore.Score > scoreRange[1] {
+					break
+				}
+				visited = true
+				next, err := visit(hashScore)
+				if err != nil || !next {
+					return err
+				}
+			}
+			if len(hashScores) < walkPageSize || hashScores[len(hashScores)-1].Score >= scoreRange[1] {
+				break
+			}
+			startScore = hashScores[len(hashScores)-1].Score + 1
+		}
+	}
+	if !visited {
+		return ErrVirtualNodeNotExists
+	}
+	return nil
+}
+
+// 获取数据所属的n个不同的真实节点用于读取，用于多副本存储
+// 从数据score开始顺时针查找，跳过已经选中的真实节点的其他虚拟节点以及不可用、不健康的节点，可用节点不足n个时返回全部可用节点
+// 与GetNode一样不跳过下线中的节点，写入新数据请使用GetNodesForWrite
+func (c *ConsistentHash) GetNodes(ctx context.Context, dataKey string, n int) (nodeNames []string, err error) {
+	return c.getNodes(ctx, dataKey, n, false)
+}
+
+// 获取写入新数据的n个不同的真实节点，与GetNodes相比还会跳过下线中的节点
+func (c *ConsistentHash) GetNodesForWrite(ctx context.Context, dataKey string, n int) (nodeNames []string, err error) {
+	return c.getNodes(ctx, dataKey, n, true)
+}
+
+func (c *ConsistentHash) getNodes(ctx context.Context, dataKey string, n int, forWrite bool) (nodeNames []string, err error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+	nodes, err := c.getRealNodeInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dataScore := c.score(dataKey)
+	nodeNames = make([]string, 0, min(n, len(nodes)))
+	err = c.walkNodes(ctx, dataScore, func(nodeName string) bool {
+		if !c.isAvailable(ctx, nodeName, nodes, forWrite) {
+			return true
+		}
+		nodeNames = append(nodeNames, nodeName)
+		return len(nodeNames) < n
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.dataKeyStore != nil && c.opts.recordOnGetNode {
+		if err := c.opts.dataKeyStore.RegisterKey(ctx, dataKey, dataScore); err != nil {
+			return nil, err
+		}
+	}
+	return nodeNames, nil
+}